@@ -0,0 +1,105 @@
+package pgxtras
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// structCopySource is a pgx.CopyFromSource whose rows are structs of type T,
+// got one at a time from next. The values of a row are pointers to the
+// fields of value, which holds the current struct, so that neither the
+// values slice nor its elements need to be allocated again for each row.
+// That's safe because pgx encodes each row before asking for the next one.
+type structCopySource[T any] struct {
+	next    func() (T, bool)
+	columns []structColumn
+	value   T
+	values  []any
+}
+
+func newStructCopySource[T any](columns []structColumn, next func() (T, bool)) *structCopySource[T] {
+	return &structCopySource[T]{
+		next:    next,
+		columns: columns,
+		values:  make([]any, len(columns)),
+	}
+}
+
+func (s *structCopySource[T]) Next() bool {
+	var ok bool
+	s.value, ok = s.next()
+	return ok
+}
+
+func (s *structCopySource[T]) Values() ([]any, error) {
+	v := reflect.ValueOf(&s.value).Elem()
+	for i, col := range s.columns {
+		fv, ok := fieldByIndexNoAlloc(v, col.index)
+		if ok {
+			s.values[i] = fv.Addr().Interface()
+		} else {
+			s.values[i] = nil
+		}
+	}
+	return s.values, nil
+}
+
+func (s *structCopySource[T]) Err() error {
+	return nil
+}
+
+// copyColumns returns the columns of T that are copied to, which are all
+// of them but the readonly ones, along with their names.
+func copyColumns[T any]() ([]structColumn, []string, error) {
+	structType := reflect.TypeOf((*T)(nil)).Elem()
+	columns, err := structColumns(structType)
+	if err != nil {
+		return nil, nil, err
+	}
+	var copied []structColumn
+	var names []string
+	for _, col := range columns {
+		if col.readOnly {
+			continue
+		}
+		copied = append(copied, col)
+		names = append(names, col.name)
+	}
+	if len(copied) == 0 {
+		return nil, nil, fmt.Errorf("struct %s has no columns to copy to", structType)
+	}
+	return copied, names, nil
+}
+
+// copyStructs copies the structs returned by next to table using COPY.
+func copyStructs[T any](ctx context.Context, db CopyFromer, table string, next func() (T, bool)) (int64, error) {
+	columns, names, err := copyColumns[T]()
+	if err != nil {
+		return 0, err
+	}
+	return db.CopyFrom(ctx, pgx.Identifier(strings.Split(table, ".")), names, newStructCopySource(columns, next))
+}
+
+// CopyStructs copies values, which must be structs, into table using the
+// COPY protocol, which is the quickest way to load a lot of rows, and
+// returns how many rows were copied. Fields are written to columns following
+// the same rules as Insert, except that, since every row that is copied
+// has the same columns, the "omitempty" option has no effect: a zero value
+// is copied as is. Fields with the "readonly" option are still never written.
+//
+//	n, err := pgxtras.CopyStructs(ctx, conn, "users", users)
+func CopyStructs[T any](ctx context.Context, db CopyFromer, table string, values []T) (int64, error) {
+	i := 0
+	return copyStructs(ctx, db, table, func() (T, bool) {
+		if i >= len(values) {
+			var zero T
+			return zero, false
+		}
+		i++
+		return values[i-1], true
+	})
+}
@@ -0,0 +1,58 @@
+package pgxtras_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/manniwood/pgxtras"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type copyPerson struct {
+	ID       int32 `db:"id,readonly"`
+	Name     string
+	Nickname *string
+	Address  struct {
+		City string
+	} `db:"address,inline"`
+}
+
+func TestCopyStructs(t *testing.T) {
+	runWithUsersTable(t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		jj := "JJ"
+		people := []copyPerson{
+			{Name: "Joe", Nickname: &jj},
+			{Name: "Ann"},
+		}
+		people[0].Address.City = "Toronto"
+		people[1].Address.City = "Ottawa"
+
+		n, err := pgxtras.CopyStructs(ctx, conn, "users", people)
+		require.NoError(t, err)
+		assert.EqualValues(t, 2, n)
+
+		copied := readUsers[copyPerson](t, ctx, conn)
+		require.Len(t, copied, 2)
+		assert.Equal(t, int32(1), copied[0].ID)
+		assert.Equal(t, "Joe", copied[0].Name)
+		require.NotNil(t, copied[0].Nickname)
+		assert.Equal(t, "JJ", *copied[0].Nickname)
+		assert.Equal(t, "Toronto", copied[0].Address.City)
+		assert.Equal(t, "Ann", copied[1].Name)
+		assert.Nil(t, copied[1].Nickname)
+		assert.Equal(t, "Ottawa", copied[1].Address.City)
+	})
+}
+
+func TestCopyStructsNoColumns(t *testing.T) {
+	type serial struct {
+		ID int32 `db:"id,readonly"`
+	}
+
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		_, err := pgxtras.CopyStructs(ctx, conn, "serials", []serial{{}})
+		assert.EqualError(t, err, "struct pgxtras_test.serial has no columns to copy to")
+	})
+}
@@ -0,0 +1,27 @@
+//go:build go1.23
+
+package pgxtras
+
+import (
+	"context"
+	"iter"
+)
+
+// CopyStructsSeq is CopyStructs for structs that come from an iterator
+// rather than a slice, so that they can be streamed into table without
+// being held in memory all at once, such as when they are read from a file:
+//
+//	n, err := pgxtras.CopyStructsSeq(ctx, conn, "users", func(yield func(User) bool) {
+//		for scanner.Scan() {
+//			if !yield(parseUser(scanner.Text())) {
+//				return
+//			}
+//		}
+//	})
+//
+// seq is stopped early if the copy fails.
+func CopyStructsSeq[T any](ctx context.Context, db CopyFromer, table string, seq iter.Seq[T]) (int64, error) {
+	next, stop := iter.Pull(seq)
+	defer stop()
+	return copyStructs(ctx, db, table, next)
+}
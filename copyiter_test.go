@@ -0,0 +1,37 @@
+//go:build go1.23
+
+package pgxtras_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/manniwood/pgxtras"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyStructsSeq(t *testing.T) {
+	runWithUsersTable(t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		people := func(yield func(copyPerson) bool) {
+			for i := 1; i <= 1000; i++ {
+				var p copyPerson
+				p.Name = fmt.Sprintf("Person %d", i)
+				p.Address.City = "Toronto"
+				if !yield(p) {
+					return
+				}
+			}
+		}
+
+		n, err := pgxtras.CopyStructsSeq(ctx, conn, "users", people)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1000, n)
+
+		copied := readUsers[copyPerson](t, ctx, conn)
+		require.Len(t, copied, 1000)
+		assert.Equal(t, "Person 1000", copied[999].Name)
+	})
+}
@@ -0,0 +1,121 @@
+package pgxtras
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var cursorSeq uint64
+
+// Cursor reads the results of a query a chunk at a time using a server-side
+// cursor, so that neither the client nor the server has to hold the whole
+// result at once. Each chunk of rows is scanned using a RowToFunc, just as
+// pgx.CollectRows does. Cursors only live as long as the transaction they
+// were declared in, so a Cursor is made from a pgx.Tx.
+//
+// A Cursor is not safe for concurrent use.
+type Cursor[T any] struct {
+	tx        pgx.Tx
+	name      string
+	fn        pgx.RowToFunc[T]
+	fetchSize int
+	done      bool
+	closed    bool
+}
+
+// NewCursor declares a cursor for sql (with args) in tx, which will be
+// fetched from fetchSize rows at a time, each row being scanned using fn.
+// For instance,
+//
+//	cursor, err := pgxtras.NewCursor(ctx, tx, pgxtras.RowToStructBySimpleName[User], 1000, `select * from users`)
+//	if err != nil {
+//		return err
+//	}
+//	defer cursor.Close(ctx)
+//	err = cursor.ForEach(ctx, func(users []User) error {
+//		// ...
+//		return nil
+//	})
+func NewCursor[T any](ctx context.Context, tx pgx.Tx, fn pgx.RowToFunc[T], fetchSize int, sql string, args ...any) (*Cursor[T], error) {
+	if fetchSize <= 0 {
+		return nil, fmt.Errorf("fetch size must be positive, but got %d", fetchSize)
+	}
+
+	name := fmt.Sprintf("pgxtras_cursor_%d", atomic.AddUint64(&cursorSeq, 1))
+	// Every cursor has a name of its own, so the statements that use it are
+	// run as unnamed statements, to keep them out of the statement cache.
+	// (pgx already runs statements without arguments, such as CLOSE, using
+	// the simple protocol, which caches nothing.)
+	args = append([]any{pgx.QueryExecModeDescribeExec}, args...)
+	_, err := tx.Exec(ctx, "declare "+pgx.Identifier{name}.Sanitize()+" no scroll cursor for "+sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &Cursor[T]{tx: tx, name: name, fn: fn, fetchSize: fetchSize}, nil
+}
+
+// Next fetches the next chunk of rows, of at most the cursor's fetch size,
+// and returns what fn made of them. Once the rows are used up, Next closes
+// the cursor and returns an empty slice. If Next fails (including because
+// ctx is cancelled), it closes the cursor as well as it can, and returns
+// the error.
+func (c *Cursor[T]) Next(ctx context.Context) ([]T, error) {
+	if c.done {
+		return nil, nil
+	}
+
+	rows, err := c.tx.Query(ctx, fmt.Sprintf("fetch forward %d from %s", c.fetchSize, pgx.Identifier{c.name}.Sanitize()), pgx.QueryExecModeDescribeExec)
+	var values []T
+	if err == nil {
+		values, err = pgx.CollectRows(rows, c.fn)
+	}
+	if err != nil {
+		c.done = true
+		// The transaction is likely aborted, in which case the cursor
+		// goes when it is rolled back, so there's no error worth reporting.
+		_ = c.Close(context.Background())
+		return nil, err
+	}
+
+	if len(values) < c.fetchSize {
+		c.done = true
+		if err := c.Close(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// ForEach calls fn with each chunk of rows in turn, until the rows are
+// used up or there is an error, and then closes the cursor. If fn returns
+// an error, ForEach returns it.
+func (c *Cursor[T]) ForEach(ctx context.Context, fn func([]T) error) error {
+	for {
+		values, err := c.Next(ctx)
+		if err != nil {
+			return err
+		}
+		if len(values) == 0 {
+			return nil
+		}
+		if err := fn(values); err != nil {
+			_ = c.Close(ctx)
+			return err
+		}
+	}
+}
+
+// Close closes the cursor, if it isn't already closed. It is safe to call
+// more than once, so it can be deferred.
+func (c *Cursor[T]) Close(ctx context.Context) error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	c.done = true
+	_, err := c.tx.Exec(ctx, "close "+pgx.Identifier{c.name}.Sanitize())
+	return err
+}
@@ -0,0 +1,83 @@
+package pgxtras_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/manniwood/pgxtras"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor(t *testing.T) {
+	type person struct {
+		ID   int32
+		Name string
+	}
+
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		tx, err := conn.Begin(ctx)
+		require.NoError(t, err)
+		defer tx.Rollback(ctx)
+
+		var prepared, preparedBefore int32
+		const countPrepared = `select count(*) from pg_prepared_statements`
+		require.NoError(t, tx.QueryRow(ctx, countPrepared).Scan(&preparedBefore))
+
+		cursor, err := pgxtras.NewCursor(ctx, tx, pgxtras.RowToStructBySimpleName[person], 10,
+			`select n as id, 'Joe' as name from generate_series(1, $1::int4) n`, 25)
+		require.NoError(t, err)
+		defer cursor.Close(ctx)
+
+		var chunkLens []int
+		var ids []int32
+		err = cursor.ForEach(ctx, func(people []person) error {
+			chunkLens = append(chunkLens, len(people))
+			for _, p := range people {
+				ids = append(ids, p.ID)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []int{10, 10, 5}, chunkLens)
+		assert.Len(t, ids, 25)
+		assert.Equal(t, int32(25), ids[24])
+
+		// the cursor's statements aren't left in the statement cache
+		require.NoError(t, tx.QueryRow(ctx, countPrepared).Scan(&prepared))
+		assert.Equal(t, preparedBefore, prepared)
+
+		var open int32
+		require.NoError(t, tx.QueryRow(ctx, `select count(*) from pg_cursors`).Scan(&open))
+		assert.Equal(t, int32(0), open)
+
+		people, err := cursor.Next(ctx)
+		assert.NoError(t, err)
+		assert.Empty(t, people)
+	})
+}
+
+func TestCursorStopsOnError(t *testing.T) {
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		tx, err := conn.Begin(ctx)
+		require.NoError(t, err)
+		defer tx.Rollback(ctx)
+
+		cursor, err := pgxtras.NewCursor(ctx, tx, pgx.RowTo[int32], 10, `select n from generate_series(1, 100) n`)
+		require.NoError(t, err)
+
+		calls := 0
+		err = cursor.ForEach(ctx, func(ns []int32) error {
+			calls++
+			return errors.New("sink is full")
+		})
+		assert.EqualError(t, err, "sink is full")
+		assert.Equal(t, 1, calls)
+
+		var open int32
+		require.NoError(t, tx.QueryRow(ctx, `select count(*) from pg_cursors`).Scan(&open))
+		assert.Equal(t, int32(0), open)
+	})
+}
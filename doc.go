@@ -33,6 +33,107 @@ if the env var was present but set to the empty string, or false if
 the env var truly was not present. Also, when getting a value from a map,
 the "comma-OK" idiom can be used for the same purpose.
 
+`pgxtras.CollectExactlyOneRow()` and `pgxtras.CollectAtMostOneRowOK()`
+
+There's another thing about `pgx.CollectOneRow()` (and `pgxtras.CollectOneRowOK()`):
+if the query returns more than one row, they quietly hand back the first one
+and throw the rest away. When a lookup that is supposed to be unique turns out
+not to be, I would rather hear about it.
+
+`pgxtras.CollectExactlyOneRow()` works like `pgx.CollectOneRow()`, and
+`pgxtras.CollectAtMostOneRowOK()` works like `pgxtras.CollectOneRowOK()`,
+except that they read every row, and if there was more than one, they return
+a `*pgxtras.TooManyRowsError` saying how many there were. `errors.Is(err, pgxtras.ErrTooManyRows)`
+is true of such an error.
+
+`pgxtras.Rows()` and `pgxtras.RowsSeq()`
+
+`pgx.CollectRows()` and friends put every row in memory at once, which is
+no good for exporting a few million rows. With Go 1.23 or later,
+`pgxtras.Rows()` turns rows and any `pgx.RowToFunc` into an iterator that
+hands over one row at a time:
+
+	rows, _ := conn.Query(ctx, `select * from users`)
+	for user, err := range pgxtras.Rows(rows, pgxtras.RowToStructBySimpleName[User]) {
+		if err != nil {
+			return err
+		}
+		// ...
+	}
+
+Any error, including `rows.Err()` at the end, comes out of the loop, and
+the rows are closed when the loop ends, even if you break out of it early.
+`pgxtras.RowsSeq()` is the same thing for those who prefer to check for an
+error once, after the loop.
+
+`pgxtras.CollectRowsToMap()` and `pgxtras.CollectRowsGrouped()`
+
+I often collect rows into a slice only to loop over the slice to build a
+map by ID, or a map from each parent's ID to a slice of its children.
+`pgxtras.CollectRowsToMap()` and `pgxtras.CollectRowsGrouped()` skip the
+middle step. They take any `pgx.RowToFunc`, plus a function that returns
+the key of each result:
+
+	usersByID, err := pgxtras.CollectRowsToMap(rows, pgxtras.RowToAddrOfStructBySimpleName[User],
+		func(u *User) int64 { return u.ID })
+
+`pgxtras.CollectRowsToMap()` returns a `*pgxtras.DuplicateKeyError` (which
+`errors.Is(err, pgxtras.ErrDuplicateKey)` is true of) if two rows have the
+same key, while `pgxtras.CollectRowsGrouped()` returns a map of slices,
+with the results for each key in the order their rows came back.
+
+`pgxtras.CollectRowsInBatches()`
+
+Somewhere between a row at a time and every row at once is a batch at a
+time, which is just right for ETL jobs that pass rows on to `CopyFrom()`
+or to some web service. `pgxtras.CollectRowsInBatches()` takes any
+`pgx.RowToFunc`, a batch size, and a function that is handed each batch
+of results in turn. The same slice is reused for every batch, so memory
+use stays put; don't hang on to a batch after your function returns. If
+your function returns an error, collection stops and the rows are closed.
+
+`pgxtras.Cursor`
+
+Even `pgxtras.Rows()` has the whole result of a query streamed over the
+wire as fast as Postgres can send it. For really long exports, a server-side
+cursor lets you ask for the next chunk of rows only when you're ready for it.
+`pgxtras.NewCursor()` declares a cursor in a transaction, and its `Next()`
+and `ForEach()` methods fetch a chunk of rows at a time, scanning them using
+any `pgx.RowToFunc`:
+
+	cursor, err := pgxtras.NewCursor(ctx, tx, pgxtras.RowToStructBySimpleName[User], 1000, `select * from users`)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	err = cursor.ForEach(ctx, func(users []User) error {
+		// ...
+		return nil
+	})
+
+The cursor is closed once the rows are used up, or something goes wrong.
+
+`pgxtras.Paginate()`
+
+Every list endpoint I write ends up with keyset pagination done by hand:
+instead of `OFFSET`, which gets slower the further in you go, each page
+picks up after the sort keys of the last row of the page before.
+`pgxtras.Paginate()` does it for any query and any `pgx.RowToFunc`:
+
+	page, err := pgxtras.Paginate(ctx, conn, pgxtras.RowToStructBySimpleName[User],
+		pgxtras.PageRequest{
+			Keys:  []pgxtras.SortKey{{Column: "created_at", Desc: true}, {Column: "id"}},
+			Size:  50,
+			Token: r.URL.Query().Get("page"),
+		},
+		`select id, name, created_at from users where org_id = $1`, orgID)
+
+`page.Items` holds the users, and `page.NextToken` and `page.PrevToken` are
+opaque tokens (empty if there is no such page) to hand back as `Token` to get
+the next and previous pages. Sort keys can mix ascending and descending order,
+and can be NULL. The last sort key should be unique (a primary key is good),
+so that no two rows have the same keys.
+
 `pgxtras.RowToStructBySnakeToCamelName()` and `pgxtras.RowToAddrOfStructBySnakeToCamelName()`
 
 The pgx library has convenience functions `pgx.RowToStructByName()` and
@@ -90,5 +191,348 @@ each other by lowercasing and stripping all underscores, like so:
 This way of determining which SQL column names go with which Go struct field
 names should cover a lot more of the standard naming conventions of both
 languages.
+
+`pgxtras.SnakeToCamel()` and `pgxtras.CamelToSnake()`
+
+Alternatively, `pgxtras.SnakeToCamelWithInitialisms()` takes a list of initialisms
+to write in all capitals, so that `pgxtras.SnakeToCamelWithInitialisms("user_id", pgxtras.CommonInitialisms...)`
+gives `UserID`, and `http_address` gives `HTTPAddress`. To scan using that
+translation, use `pgxtras.RowToStructWith()` (below) with
+`pgxtras.NewSnakeToCamelNameMapper(pgxtras.CommonInitialisms...)`, or your
+own list of initialisms.
+
+Going the other way, `pgxtras.CamelToSnake()` translates `HTTPAddress` to
+`http_address` and `UserID` to `user_id`, which is handy for generating SQL
+from struct field names.
+
+`pgxtras.RowToStructWith()` and `pgxtras.RowToAddrOfStructWith()`
+
+If neither of the above naming conventions matches yours, you can supply
+your own. `pgxtras.RowToStructWith()` and `pgxtras.RowToAddrOfStructWith()`
+take a `pgxtras.NameMapper`, which decides whether a given column name goes
+with a given struct field name, and return a function that can be handed to
+`pgx.CollectRows()` and friends.
+
+For instance, if all of your legacy column names start with `tbl_`:
+
+	var rowToUser = pgxtras.RowToStructWith[User](pgxtras.NameMapperFunc(
+		func(columnName, fieldName string) bool {
+			return pgxtras.SimpleNameMapper.Match(strings.TrimPrefix(columnName, "tbl_"), fieldName)
+		}))
+
+	users, err := pgx.CollectRows(rows, rowToUser)
+
+`pgxtras.RowToStructBySnakeToCamelName()` and `pgxtras.RowToStructBySimpleName()`
+are just `pgxtras.RowToStructWith()` using `pgxtras.SnakeToCamelNameMapper`
+and `pgxtras.SimpleNameMapper`, respectively.
+
+All of these functions work out which column goes with which struct field
+once per struct type and query, and then reuse that mapping for every row,
+so they are not much slower than scanning into variables by hand.
+
+`pgxtras.RowToStructBySimpleNameLax()` and `pgxtras.RowToAddrOfStructBySimpleNameLax()`
+
+All of the struct scanning functions above insist that every column in a
+returned row has a struct field, and that every struct field has a column.
+That catches a lot of mistakes, but it gets in the way if you reuse one
+struct (say, `User`) across many queries that each select a different
+subset of its fields.
+
+`pgxtras.RowToStructBySimpleNameLax()` and `pgxtras.RowToAddrOfStructBySimpleNameLax()`
+leave any struct field that has no column at its zero value, and silently
+discard any column that has no struct field. `pgxtras.RowToStructWith()`
+does the same when given the `pgxtras.LaxMatching()` option.
+
+Going the other way, `pgxtras.RowToStructWith()` given the
+`pgxtras.ReportAllMismatches()` option reports every unmatched column and
+struct field in one error, instead of just the first one it finds.
+
+A struct field that matches more than one column (say, `UserID` and the
+columns `user_id` and `userid`, or two `id` columns from a join), or a
+column that matches more than one struct field, is an error rather than
+a coin toss, even with `pgxtras.LaxMatching()`. (A field of an embedded
+struct does lose out to a field of the outer struct, just as in Go.)
+`pgxtras.RowToStructWith()` given the `pgxtras.PreferExactNames()` option
+settles such ties in favour of the column whose name is exactly that of
+the struct field, ignoring case, and leaves the other column unscanned
+(with or without `pgxtras.LaxMatching()`).
+
+# Embedded and nested structs
+
+All of the struct scanning functions above treat the fields of an embedded
+struct, or of an embedded pointer to a struct, as fields of the outer
+struct. An embedded pointer is only allocated if some column is scanned
+into it.
+
+A named struct field is normally scanned into as a single value (after
+all, `time.Time` is a struct). To have its fields treated as fields of
+the outer struct instead, tag it with the `inline` option, or with a
+`prefix=` option to say what the names of its columns start with.
+Giving a name along with `inline` is shorthand for a prefix of that
+name followed by an underscore. So, given
+
+	type Address struct {
+		Street string
+		City   string
+	}
+
+	type User struct {
+		Name    string
+		Address Address  `db:",prefix=addr_"`
+		Home    *Address `db:"home,inline"`
+	}
+
+the columns `addr_street` and `addr_city` are scanned into `Address.Street`
+and `Address.City`, and `home_street` and `home_city` are scanned into
+`Home.Street` and `Home.City`.
+
+`pgxtras.RowToStructPair()` and `pgxtras.MultiStructScanner`
+
+When joining two tables, it is nice to be able to reuse the structs that
+you already have for each table, rather than writing one more struct just
+for the join. `pgxtras.RowToStructPair()` splits the columns of each row
+between two structs, either by the prefix of the column names:
+
+	rows, _ := conn.Query(ctx, `
+	select u.id as u_id, u.name as u_name, o.id as o_id, o.name as o_name
+	  from users u join orgs o on o.id = u.org_id`)
+	pairs, err := pgx.CollectRows(rows, pgxtras.RowToStructPair[User, Org](pgxtras.SplitByPrefix("u_", "o_")))
+	// pairs[i].First is a User, and pairs[i].Second is an Org
+
+or by column position, using `pgxtras.SplitByPosition()`. Columns are then
+matched to struct fields just as `pgxtras.RowToStructBySimpleName()` does.
+
+To split a row between more than two structs, use a `pgxtras.MultiStructScanner`,
+which takes a `pgxtras.NameMapper` and options just like `pgxtras.RowToStructWith()`.
+
+# Struct scanning errors
+
+When the struct scanning functions above can't match the columns of a row
+to the fields of a struct, that's a bug in the query or in the struct, not
+a problem with the data. So they return a `*pgxtras.MappingError`, which
+says what kind of mismatch it was (`pgxtras.MissingColumn`,
+`pgxtras.UnmappedColumn`, `pgxtras.AmbiguousColumn` or `pgxtras.NotAStruct`)
+along with the struct type, field name, and column name and position involved.
+With the `pgxtras.ReportAllMismatches()` option, several of them come back
+together as `pgxtras.MappingErrors`.
+
+When a column's value can't be decoded into its struct field, the error from
+pgx is wrapped in a `*pgxtras.ColumnScanError`, which adds the column name and
+its Postgres type OID, and the struct field name.
+
+Tell them apart with `errors.As()`:
+
+	var mappingErr *pgxtras.MappingError
+	if errors.As(err, &mappingErr) {
+		// fix the query or the struct
+	}
+
+`pgxtras.ValidateStructForQuery()`
+
+Mapping errors only turn up when a query is actually run, which might be
+in production, at 3 a.m., on the one code path nobody tested.
+`pgxtras.ValidateStructForQuery()` finds them sooner. It prepares a query
+without running it, looks at the columns Postgres says it will return and
+their types, and checks that every column matches a struct field, and that
+every column's type can be scanned into the Go type of its field:
+
+	err := pgxtras.ValidateStructForQuery[User](ctx, conn,
+		`select id, name from users where id = $1`, pgxtras.SimpleNameMapper)
+
+It reports everything that's wrong at once. A column whose type can't be
+scanned into its field is a `*pgxtras.MappingError` of kind
+`pgxtras.IncompatibleType`. I like to run it over every query in my unit
+tests against a local Postgres, so that mistakes fail the build rather than
+the deploy.
+
+`pgxtras.RowToMapStrStr()`
+
+pgx has `pgx.RowToMap()` which returns a `map[string]any`.
+
+pgxtras has `pgxtras.RowToMapStrStr()` which returns a `map[string]string`.
+Internally, `RowToMapStrStr()` just uses `fmt.Sprintf("%v", val)`. If you want
+to control the string representation of your column, I recommend doing so
+in your SQL statement: if every column in your SQL result set is already
+of Postgres type `text`, then you know what you will be getting when
+Go does `fmt.Sprintf("%v", val)` to it.
+
+`pgxtras.RowToMapStrStr()` is fine for quick debugging output, but
+`fmt.Sprintf("%v", val)` shows NULL as `<nil>`, bytea as a list of decimal
+numbers, and numerics as the insides of a struct. So there are a few more
+choices:
+
+  - `pgxtras.RowToMapStrText()` returns each value as text, with NULL as the
+    empty string. Values that Postgres sends as text come back just as `psql`
+    shows them, but pgx asks for some types, such as `timestamptz` and
+    `interval`, in binary, and its own text for those isn't quite Postgres's
+    (a `timestamptz` is always in UTC, for instance). Pass
+    `pgx.QueryResultFormats{pgx.TextFormatCode}` as the first argument to
+    `Query()` to have Postgres send everything as text, and you get exactly
+    what `psql` shows.
+  - `pgxtras.RowToMapStrPtrStr()` does the same, but returns a
+    `map[string]*string`, with NULL as a nil pointer, so that NULL can be told
+    apart from the empty string.
+  - `pgxtras.RowToMapStrStrWith()` takes a `pgxtras.FormatOptions` saying how
+    to show NULL and timestamps, and does a better job than `fmt` of showing
+    bytea, uuids and numerics.
+
+`pgxtras.WithTx()`
+
+pgx has `pgx.BeginTxFunc()`, which commits if your function returns nil and
+rolls back otherwise. `pgxtras.WithTx()` does the same, and a bit more. When
+you ask for the `pgx.RepeatableRead` or `pgx.Serializable` isolation level,
+Postgres expects you to retry transactions that fail with a serialization
+failure or deadlock (SQLSTATE `40001` or `40P01`), so `pgxtras.WithTx()`
+does just that, backing off a little longer before each try. The
+`pgxtras.MaxAttempts()` and `pgxtras.RetryBackoff()` options say how many
+times, and how long to wait:
+
+	err := pgxtras.WithTx(ctx, pool, pgx.TxOptions{IsoLevel: pgx.Serializable}, func(tx pgx.Tx) error {
+		// ...
+	}, pgxtras.MaxAttempts(5))
+
+Given a `pgx.Tx` rather than a connection or pool, `pgxtras.WithTx()` runs
+your function in a savepoint instead, so functions that use it can call one
+another without worrying about who began the transaction.
+
+`pgxtras.Nested()` is the same idea for functions that take a
+`pgxtras.QuerierExecer`: given a connection or pool, it runs your function
+in a transaction of its own, and given a `pgx.Tx`, in a savepoint, which
+is rolled back if your function returns an error or panics, leaving the
+rest of the transaction alone.
+
+`pgxtras.IsUniqueViolation()` and friends
+
+I'm tired of writing `errors.As(err, &pgErr) && pgErr.Code == "23505"`.
+So there are predicates for the SQLSTATEs that I find myself checking for:
+`pgxtras.IsUniqueViolation()`, `pgxtras.IsForeignKeyViolation()`,
+`pgxtras.IsNotNullViolation()`, `pgxtras.IsCheckViolation()`,
+`pgxtras.IsSerializationFailure()`, `pgxtras.IsDeadlock()`,
+`pgxtras.IsQueryCanceled()`, and `pgxtras.IsConnectionException()`, along
+with `pgxtras.SQLState()` for everything else, and
+`pgxtras.ConstraintName()`, `pgxtras.TableName()` and `pgxtras.ColumnName()`
+to find out what the error was about. They all see through wrapped errors.
+
+`pgxtras.IsRetryable()` says whether a transaction that failed with an
+error can simply be run again; it's what `pgxtras.WithTx()` uses to decide
+whether to retry.
+
+`pgxtras.Insert()` and `pgxtras.InsertReturning()`
+
+Scanning structs is only half of it; I also want to write them. Given a
+struct, `pgxtras.Insert()` inserts it into a table as a new row, following
+the same `db` tag rules as the struct scanning functions (including
+embedded and nested structs), and writing fields that have no name in a
+`db` tag to the snake_case version of their name (see
+`pgxtras.CamelToSnake()`). Two more `db` tag options say what not to write:
+`omitempty` leaves a field out when it has its zero value, so the column
+gets its default, and `readonly` never writes a field at all, which is what
+you want for serial IDs and generated columns. `pgxtras.InsertReturning()`
+does the same, and then reads the whole row back into a copy of your
+struct, so you get the ID and the defaults:
+
+	type User struct {
+		ID        int64     `db:"id,readonly"`
+		Name      string
+		CreatedAt time.Time `db:",omitempty"`
+	}
+
+	user, err := pgxtras.InsertReturning(ctx, conn, "users", User{Name: "Joe"})
+
+`pgxtras.Update()` and `pgxtras.Upsert()`
+
+These go with `pgxtras.Insert()`, and follow the same rules. `pgxtras.Update()`
+writes a struct to the rows whose key columns match it, and returns the
+command tag; I make you name the key columns, so that you can't update the
+whole table by forgetting to:
+
+	tag, err := pgxtras.Update(ctx, conn, "users", user, "id")
+
+`pgxtras.Upsert()` inserts a struct, but updates the row it conflicts with
+instead, if there is one. Say what it conflicts on with
+`pgxtras.ConflictColumns()` or `pgxtras.ConflictConstraint()`; every other
+column that was inserted is set from `excluded`, except for any that you
+name with `pgxtras.ExcludeFromUpdate()`:
+
+	tag, err := pgxtras.Upsert(ctx, conn, "users", user,
+		pgxtras.ConflictColumns("email"), pgxtras.ExcludeFromUpdate("created_at"))
+
+`pgxtras.UpdateReturning()` and `pgxtras.UpsertReturning()` read the row
+back into a copy of your struct, as `pgxtras.InsertReturning()` does.
+
+`pgxtras.CopyStructs()` and `pgxtras.CopyStructsSeq()`
+
+`pgx.Conn.CopyFrom()` is the quickest way to load a lot of rows, but it
+wants them as `[][]any`, and I already have them as structs.
+`pgxtras.CopyStructs()` copies a slice of structs into a table, writing
+fields to columns by the same rules as `pgxtras.Insert()` (though
+`omitempty` does nothing, since every row that is copied has the same
+columns). It hands pgx pointers into the struct it's on, rather than
+building a `[]any` for each row:
+
+	n, err := pgxtras.CopyStructs(ctx, conn, "users", users)
+
+`pgxtras.CopyStructsSeq()` does the same with an `iter.Seq[T]`, so that rows
+can be streamed in without being held in memory all at once. Like
+`pgxtras.Rows()`, it needs Go 1.23 or later.
+
+`pgxtras.InsertMany()`
+
+Sometimes COPY won't do, because some of the rows might already be there.
+`pgxtras.InsertMany()` inserts a slice of structs using as few multi-row
+INSERT statements as it can, splitting them up so that none of them goes
+over the 65535 parameters that Postgres allows. `pgxtras.OnConflictDoNothing()`
+and `pgxtras.OnConflictUpdate()` (which takes the same options as
+`pgxtras.Upsert()`) say what to do about rows that are already there, and
+`pgxtras.InBatch()` sends all of the statements at once in a `pgx.Batch`,
+which Postgres runs in a transaction of its own:
+
+	n, err := pgxtras.InsertMany(ctx, conn, "users", users,
+		pgxtras.OnConflictUpdate(pgxtras.ConflictColumns("email")),
+		pgxtras.InBatch())
+
+Every row has the same columns, so a field with the `omitempty` option is
+inserted as `DEFAULT` when it's empty. For the same reason,
+`pgxtras.OnConflictUpdate()` never updates `omitempty` columns: a row whose
+field was empty would otherwise reset the existing row's column to its default.
+
+`pgxtras.Named()`
+
+I lose count of `$1`, `$2`, ... `$14` in long queries. `pgxtras.Named()`
+lets a query use named parameters, like `:user_id` or `@user_id`, instead.
+It's a `pgx.QueryRewriter`, so it works anywhere pgx takes query arguments:
+pass it as the only argument to `Query()`, `QueryRow()` or `Exec()` on a
+connection, pool or transaction, or to `pgx.Batch.Queue()`. The values come
+from a map, or from a struct whose fields match the parameters by their
+"simple" names, as with `pgxtras.RowToStructBySimpleName()`:
+
+	rows, err := conn.Query(ctx,
+		`select * from orders where user_id = :user_id and placed_at >= :since::date`,
+		pgxtras.Named(map[string]any{"user_id": 42, "since": since}))
+
+It knows to leave alone string literals (including dollar-quoted ones),
+quoted identifiers, comments, `::` casts, and array slices like `arr[lo:hi]`
+and `arr[:hi]` (use `@` for a parameter in a slice, as in `arr[@lo:@hi]`).
+It only parses each query once, keeping the last 1000 it has seen.
+`pgxtras.BindNamed()` does the rewriting on its own, if you need the
+positional query and arguments for something else.
+
+`pgxtras.Querier`, `pgxtras.Execer`, `pgxtras.QuerierExecer`, and friends
+
+I end up using these interfaces a lot in my code: instead of using concrete
+`pgx.Conn`, `pgxpool.Pool`, `pgx.Tx`, and `pgxpool.Tx` arguments in
+my functions and methods, I just use one of `Querier`, `Execer`, or
+`QuerierExecer` instead. This way, my data access functions and methods
+are not restricted to just using one of `pgx.Conn`, `pgxpool.Pool`, `pgx.Tx`,
+or `pgxpool.Tx`.
+
+When a function needs `QueryRow()`, `SendBatch()`, `CopyFrom()`, or `Begin()`,
+there are `QueryRower`, `Batcher`, `CopyFromer`, and `Beginner`, too. And
+`DB` combines all of them, for a function that needs a bit of everything.
+`pgxpool.Conn` satisfies them all as well.
+
+If you only intend to use these interfaces, don't bother importing this
+whole module; just cut and paste these interfaces into your own project;
+the license on this module is quite permissive.
 */
 package pgxtras
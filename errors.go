@@ -0,0 +1,221 @@
+package pgxtras
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrTooManyRows is what a *TooManyRowsError is, according to errors.Is.
+var ErrTooManyRows = errors.New("too many rows in result set")
+
+// TooManyRowsError is returned by CollectExactlyOneRow and CollectAtMostOneRowOK
+// when more than one row is returned.
+type TooManyRowsError struct {
+	// Count is how many rows were returned.
+	Count int
+}
+
+func (e *TooManyRowsError) Error() string {
+	return fmt.Sprintf("expected at most one row, but got %d", e.Count)
+}
+
+// Is reports whether target is ErrTooManyRows.
+func (e *TooManyRowsError) Is(target error) bool {
+	return target == ErrTooManyRows
+}
+
+// ErrDuplicateKey is what a *DuplicateKeyError is, according to errors.Is.
+var ErrDuplicateKey = errors.New("duplicate key in result set")
+
+// DuplicateKeyError is returned by CollectRowsToMap when more than one row has the same key.
+type DuplicateKeyError struct {
+	// Key is the key that more than one row has.
+	Key any
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("more than one row has key %v", e.Key)
+}
+
+// Is reports whether target is ErrDuplicateKey.
+func (e *DuplicateKeyError) Is(target error) bool {
+	return target == ErrDuplicateKey
+}
+
+// MappingErrorKind says what went wrong when matching returned columns to struct fields.
+type MappingErrorKind int
+
+const (
+	// MissingColumn means a struct field has no column to be scanned from.
+	MissingColumn MappingErrorKind = iota + 1
+	// UnmappedColumn means a column has no struct field to be scanned into.
+	UnmappedColumn
+	// AmbiguousColumn means a column or struct field could be matched in more than one way.
+	AmbiguousColumn
+	// NotAStruct means the destination is not a pointer to a struct.
+	NotAStruct
+	// IncompatibleType means a column's Postgres type can't be scanned into
+	// the Go type of its struct field. Only ValidateStructForQuery reports it.
+	IncompatibleType
+)
+
+func (k MappingErrorKind) String() string {
+	switch k {
+	case MissingColumn:
+		return "MissingColumn"
+	case UnmappedColumn:
+		return "UnmappedColumn"
+	case AmbiguousColumn:
+		return "AmbiguousColumn"
+	case NotAStruct:
+		return "NotAStruct"
+	case IncompatibleType:
+		return "IncompatibleType"
+	default:
+		return fmt.Sprintf("MappingErrorKind(%d)", int(k))
+	}
+}
+
+// MappingError is returned by the struct scanning functions when the columns
+// of a returned row cannot be matched to the fields of a struct. Such an error
+// is a bug in the query or the struct, rather than a problem with the data;
+// tell the two apart with errors.As:
+//
+//	var mappingErr *pgxtras.MappingError
+//	if errors.As(err, &mappingErr) {
+//		log.Printf("%s: field %q, column %q", mappingErr.Kind, mappingErr.FieldName, mappingErr.ColumnName)
+//	}
+type MappingError struct {
+	Kind MappingErrorKind
+	// StructType is the type of the struct being scanned into, if known.
+	StructType reflect.Type
+	// FieldName is the name of the struct field concerned, if any. It is the
+	// name given by the field's "db" tag, if present. The fields of nested
+	// inline structs are given as dotted paths, such as "Address.City".
+	FieldName string
+	// ColumnName and ColumnIndex identify the column concerned, if any.
+	// ColumnIndex is -1 if no column is concerned.
+	ColumnName  string
+	ColumnIndex int
+	// OtherFieldName names the second struct field that matches ColumnName,
+	// or OtherColumnName names the second column that matches FieldName,
+	// in an AmbiguousColumn error.
+	OtherFieldName  string
+	OtherColumnName string
+	// DataTypeOID and FieldType are the Postgres type of the column and the
+	// Go type of the struct field in an IncompatibleType error.
+	DataTypeOID uint32
+	FieldType   reflect.Type
+}
+
+func (e *MappingError) Error() string {
+	switch e.Kind {
+	case MissingColumn:
+		return fmt.Sprintf("no column in returned row matches struct field %s", e.FieldName)
+	case UnmappedColumn:
+		return fmt.Sprintf("struct doesn't have corresponding field to match returned column %s", e.ColumnName)
+	case AmbiguousColumn:
+		if e.OtherColumnName != "" {
+			return fmt.Sprintf("struct field %s matches both returned column %s and returned column %s", e.FieldName, e.ColumnName, e.OtherColumnName)
+		}
+		return fmt.Sprintf("returned column %s matches both struct field %s and struct field %s", e.ColumnName, e.FieldName, e.OtherFieldName)
+	case NotAStruct:
+		if e.StructType == nil {
+			return "dst not a pointer"
+		}
+		return fmt.Sprintf("dst not a pointer to a struct, but to %s", e.StructType)
+	case IncompatibleType:
+		return fmt.Sprintf("returned column %s (type OID %d) can't be scanned into struct field %s of type %s", e.ColumnName, e.DataTypeOID, e.FieldName, e.FieldType)
+	default:
+		return fmt.Sprintf("mapping error %s", e.Kind)
+	}
+}
+
+// MappingErrors is returned instead of a single MappingError when the
+// ReportAllMismatches option is in effect, or by ValidateStructForQuery,
+// and more than one thing went wrong.
+// On Go 1.20 and later, errors.As finds each of the MappingErrors inside it.
+type MappingErrors []*MappingError
+
+func (e MappingErrors) Error() string {
+	structTypes := make([]string, 0, 1)
+	seen := make(map[reflect.Type]bool)
+	mismatches := make([]string, len(e))
+	for i, err := range e {
+		mismatches[i] = err.Error()
+		if err.StructType != nil && !seen[err.StructType] {
+			seen[err.StructType] = true
+			structTypes = append(structTypes, err.StructType.String())
+		}
+	}
+	var between string
+	switch len(structTypes) {
+	case 0:
+		between = "struct"
+	case 1:
+		between = "struct " + structTypes[0]
+	default:
+		between = "structs " + strings.Join(structTypes, ", ")
+	}
+	return fmt.Sprintf("%d mismatches between %s and returned row: %s", len(e), between, strings.Join(mismatches, "; "))
+}
+
+// Unwrap returns the individual MappingErrors.
+func (e MappingErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// ColumnScanError is returned by the struct scanning functions when a
+// column's value could not be decoded into its struct field. It wraps
+// the error returned by pgx.
+type ColumnScanError struct {
+	// ColumnName, ColumnIndex and DataTypeOID identify the column
+	// and its Postgres type.
+	ColumnName  string
+	ColumnIndex int
+	DataTypeOID uint32
+	// StructType and FieldName identify the struct field being scanned into.
+	StructType reflect.Type
+	FieldName  string
+	Err        error
+}
+
+func (e *ColumnScanError) Error() string {
+	err := e.Err
+	var scanArgErr pgx.ScanArgError
+	if errors.As(err, &scanArgErr) {
+		err = scanArgErr.Err
+	}
+	return fmt.Sprintf("can't scan returned column %s (type OID %d) into struct field %s: %v", e.ColumnName, e.DataTypeOID, e.FieldName, err)
+}
+
+func (e *ColumnScanError) Unwrap() error {
+	return e.Err
+}
+
+// wrapScanError wraps an error returned by rows.Scan in a ColumnScanError
+// if it says which column could not be scanned.
+func (p *structScanPlan) wrapScanError(err error, fldDescs []pgconn.FieldDescription) error {
+	var scanArgErr pgx.ScanArgError
+	if !errors.As(err, &scanArgErr) || scanArgErr.ColumnIndex < 0 || scanArgErr.ColumnIndex >= len(fldDescs) || scanArgErr.ColumnIndex >= len(p.fieldNames) {
+		return err
+	}
+	i := scanArgErr.ColumnIndex
+	return &ColumnScanError{
+		ColumnName:  fldDescs[i].Name,
+		ColumnIndex: i,
+		DataTypeOID: fldDescs[i].DataTypeOID,
+		StructType:  p.columnStructType(i),
+		FieldName:   p.fieldNames[i],
+		Err:         err,
+	}
+}
@@ -0,0 +1,75 @@
+package pgxtras_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manniwood/pgxtras"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMappingError(t *testing.T) {
+	type person struct {
+		ID        int32
+		FirstName string
+	}
+
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		rows, _ := conn.Query(ctx, `select 1 as id`)
+		_, err := pgx.CollectRows(rows, pgxtras.RowToStructBySimpleName[person])
+		var mappingErr *pgxtras.MappingError
+		require.True(t, errors.As(err, &mappingErr))
+		assert.Equal(t, pgxtras.MissingColumn, mappingErr.Kind)
+		assert.Equal(t, reflect.TypeOf(person{}), mappingErr.StructType)
+		assert.Equal(t, "FirstName", mappingErr.FieldName)
+		assert.Equal(t, -1, mappingErr.ColumnIndex)
+
+		rows, _ = conn.Query(ctx, `select 1 as id, 'John' as first_name, null as ignore`)
+		_, err = pgx.CollectRows(rows, pgxtras.RowToStructBySimpleName[person])
+		require.True(t, errors.As(err, &mappingErr))
+		assert.Equal(t, pgxtras.UnmappedColumn, mappingErr.Kind)
+		assert.Equal(t, "ignore", mappingErr.ColumnName)
+		assert.Equal(t, 2, mappingErr.ColumnIndex)
+
+		rows, _ = conn.Query(ctx, `select 1 as id`)
+		_, err = pgx.CollectRows(rows, pgxtras.RowToStructBySimpleName[int32])
+		require.True(t, errors.As(err, &mappingErr))
+		assert.Equal(t, pgxtras.NotAStruct, mappingErr.Kind)
+
+		rows, _ = conn.Query(ctx, `select 1 as id, null as ignore`)
+		_, err = pgx.CollectRows(rows, pgxtras.RowToStructWith[person](pgxtras.SimpleNameMapper, pgxtras.ReportAllMismatches()))
+		var mappingErrs pgxtras.MappingErrors
+		require.True(t, errors.As(err, &mappingErrs))
+		require.Len(t, mappingErrs, 2)
+		assert.Equal(t, pgxtras.MissingColumn, mappingErrs[0].Kind)
+		assert.Equal(t, pgxtras.UnmappedColumn, mappingErrs[1].Kind)
+	})
+}
+
+func TestColumnScanError(t *testing.T) {
+	type person struct {
+		ID        int32
+		FirstName string
+	}
+
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		rows, _ := conn.Query(ctx, `select 1 as id, null::text as first_name`)
+		_, err := pgx.CollectRows(rows, pgxtras.RowToStructBySimpleName[person])
+		var scanErr *pgxtras.ColumnScanError
+		require.True(t, errors.As(err, &scanErr))
+		assert.Equal(t, "first_name", scanErr.ColumnName)
+		assert.Equal(t, 1, scanErr.ColumnIndex)
+		assert.Equal(t, uint32(pgtype.TextOID), scanErr.DataTypeOID)
+		assert.Equal(t, "FirstName", scanErr.FieldName)
+
+		var mappingErr *pgxtras.MappingError
+		assert.False(t, errors.As(err, &mappingErr))
+		var scanArgErr pgx.ScanArgError
+		assert.True(t, errors.As(err, &scanArgErr))
+	})
+}
@@ -0,0 +1,16 @@
+package pgxtras
+
+// Exported for the tests of package pgxtras_test.
+
+// ParseNamedCached parses sql as BindNamed does, returning what was parsed,
+// so that tests can tell whether two calls were served from the cache.
+func ParseNamedCached(sql string) (any, error) {
+	return parseNamedCached(sql)
+}
+
+// NamedStatementCacheLen returns how many rewritten queries are cached.
+func NamedStatementCacheLen() int {
+	return namedStatements.len()
+}
+
+const NamedStatementCacheSize = namedStatementCacheSize
@@ -0,0 +1,67 @@
+package pgxtras_test
+
+import (
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// staticRows is a pgx.Rows over text format values that doesn't need a
+// database, for benchmarks and tests of the scanning code alone.
+type staticRows struct {
+	typeMap  *pgtype.Map
+	fldDescs []pgconn.FieldDescription
+	data     [][][]byte
+	i        int
+	closed   bool
+}
+
+// newStaticRows returns a staticRows with the given columns, of the types
+// with the given OIDs, and rows of values.
+func newStaticRows(columns []string, oids []uint32, rows ...[]string) *staticRows {
+	r := &staticRows{typeMap: pgtype.NewMap(), i: -1}
+	for i, col := range columns {
+		r.fldDescs = append(r.fldDescs, pgconn.FieldDescription{Name: col, DataTypeOID: oids[i], Format: pgtype.TextFormatCode})
+	}
+	for _, row := range rows {
+		values := make([][]byte, len(row))
+		for i, v := range row {
+			values[i] = []byte(v)
+		}
+		r.data = append(r.data, values)
+	}
+	return r
+}
+
+func (r *staticRows) Close()                                       { r.closed = true }
+func (r *staticRows) Err() error                                   { return nil }
+func (r *staticRows) CommandTag() pgconn.CommandTag                { return pgconn.CommandTag{} }
+func (r *staticRows) FieldDescriptions() []pgconn.FieldDescription { return r.fldDescs }
+func (r *staticRows) RawValues() [][]byte                          { return r.data[r.i] }
+func (r *staticRows) Conn() *pgx.Conn                              { return nil }
+
+func (r *staticRows) Next() bool {
+	if r.closed || r.i+1 >= len(r.data) {
+		r.closed = true
+		return false
+	}
+	r.i++
+	return true
+}
+
+func (r *staticRows) Scan(dest ...any) error {
+	if len(dest) == 1 {
+		if rs, ok := dest[0].(pgx.RowScanner); ok {
+			return rs.ScanRow(r)
+		}
+	}
+	return pgx.ScanRow(r.typeMap, r.fldDescs, r.data[r.i], dest...)
+}
+
+func (r *staticRows) Values() ([]any, error) {
+	values := make([]any, len(r.data[r.i]))
+	for i, v := range r.data[r.i] {
+		values[i] = string(v)
+	}
+	return values, nil
+}
@@ -0,0 +1,43 @@
+package pgxtras_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/manniwood/pgxtras"
+	"github.com/stretchr/testify/require"
+)
+
+// createUsersTable makes the table that the tests of Insert, Update, Upsert,
+// CopyStructs and InsertMany write to. Each test writes a struct type of its
+// own, with fields for just the columns that it is about.
+const createUsersTable = `create temporary table users (
+	id serial primary key,
+	email text unique,
+	name text not null,
+	nickname text default 'anon',
+	address_city text,
+	stock int4 not null default 10,
+	created_at timestamptz not null default '2020-01-01 00:00:00Z'
+)`
+
+// runWithUsersTable runs fn as defaultConnTestRunner.RunTest does, with an
+// empty users table, which is dropped again afterward.
+func runWithUsersTable(t *testing.T, fn func(ctx context.Context, t testing.TB, conn *pgx.Conn)) {
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		_, err := conn.Exec(ctx, createUsersTable)
+		require.NoError(t, err)
+		defer conn.Exec(ctx, `drop table users`)
+		fn(ctx, t, conn)
+	})
+}
+
+// readUsers returns the rows of the users table, in order of id, each
+// scanned into a T by simple name, skipping columns that T has no field for.
+func readUsers[T any](t testing.TB, ctx context.Context, conn *pgx.Conn) []T {
+	rows, _ := conn.Query(ctx, `select * from users order by id`)
+	users, err := pgx.CollectRows(rows, pgxtras.RowToStructBySimpleNameLax[T])
+	require.NoError(t, err)
+	return users
+}
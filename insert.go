@@ -0,0 +1,321 @@
+package pgxtras
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// structColumn is a struct field that is written to (and read back from)
+// a table column by Insert and friends.
+type structColumn struct {
+	name      string
+	index     []int
+	omitEmpty bool
+	readOnly  bool
+}
+
+type structColumnsResult struct {
+	columns []structColumn
+	err     error
+}
+
+var structColumnsCache sync.Map // reflect.Type -> structColumnsResult
+
+// structColumns returns the columns that the fields of structType are
+// written to. They follow the same rules as struct scanning: unexported
+// fields and fields tagged `db:"-"` are skipped, a "db" tag gives the
+// column name, and the fields of embedded structs, and of nested structs
+// tagged with the "inline" or "prefix=" options, are treated as fields of
+// the outer struct. Fields with no name in a "db" tag are written to the
+// column named CamelToSnake of the field name.
+func structColumns(structType reflect.Type) ([]structColumn, error) {
+	if cached, ok := structColumnsCache.Load(structType); ok {
+		result := cached.(structColumnsResult)
+		return result.columns, result.err
+	}
+
+	var result structColumnsResult
+	if structType.Kind() != reflect.Struct {
+		result.err = fmt.Errorf("value must be a struct, but is %s", structType)
+	} else {
+		columns := appendStructColumns(nil, structType, nil, "")
+		result.columns, result.err = resolveStructColumns(structType, columns)
+	}
+	structColumnsCache.Store(structType, result)
+	return result.columns, result.err
+}
+
+func appendStructColumns(columns []structColumn, structType reflect.Type, parentIndex []int, colPrefix string) []structColumn {
+	for i := 0; i < structType.NumField(); i++ {
+		sf := structType.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			// Field is unexported, skip it.
+			continue
+		}
+		tag := parseDBTag(sf)
+		if tag.name == "-" {
+			// Field is ignored, skip it.
+			continue
+		}
+		index := make([]int, len(parentIndex)+1)
+		copy(index, parentIndex)
+		index[len(parentIndex)] = i
+
+		if nestedType, ok := embeddedOrInlineStruct(sf, tag); ok {
+			columns = appendStructColumns(columns, nestedType, index, colPrefix+tag.prefix)
+			continue
+		}
+		if sf.PkgPath != "" {
+			// Field is an embedded unexported type; skip it.
+			continue
+		}
+
+		colName := tag.name
+		if colName == "" {
+			colName = CamelToSnake(sf.Name)
+		}
+		columns = append(columns, structColumn{
+			name:      colPrefix + colName,
+			index:     index,
+			omitEmpty: tag.omitEmpty,
+			readOnly:  tag.readOnly,
+		})
+	}
+	return columns
+}
+
+// resolveStructColumns resolves fields that are written to the same column.
+// As with Go's own promotion of the fields of embedded structs, a field nested
+// less deeply wins over one nested more deeply; two fields nested equally
+// deeply are an error.
+func resolveStructColumns(structType reflect.Type, columns []structColumn) ([]structColumn, error) {
+	winners := make(map[string]int, len(columns))
+	for i, col := range columns {
+		j, ok := winners[col.name]
+		switch {
+		case !ok || len(col.index) < len(columns[j].index):
+			winners[col.name] = i
+		case len(col.index) == len(columns[j].index):
+			return nil, fmt.Errorf("struct %s has more than one field for column %s", structType, col.name)
+		}
+	}
+
+	resolved := make([]structColumn, 0, len(winners))
+	for i, col := range columns {
+		if winners[col.name] == i {
+			resolved = append(resolved, col)
+		}
+	}
+	return resolved, nil
+}
+
+// fieldByIndexNoAlloc is like reflect.Value.FieldByIndex, except that rather
+// than panicking when it comes across a nil pointer to a struct along the
+// way, it returns false.
+func fieldByIndexNoAlloc(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// sanitizeTableName quotes a table name, which may be qualified with a
+// schema name, such as "public.users".
+func sanitizeTableName(table string) string {
+	return pgx.Identifier(strings.Split(table, ".")).Sanitize()
+}
+
+// fieldValue returns the value of the field of value for col, or nil if the
+// field is inside a nil pointer to an embedded struct.
+func fieldValue(value reflect.Value, col structColumn) any {
+	fv, ok := fieldByIndexNoAlloc(value, col.index)
+	if !ok {
+		return nil
+	}
+	return fv.Interface()
+}
+
+// insertedColumns returns the columns of value that an INSERT writes, which
+// are all of them except readonly ones and omitempty ones whose fields have
+// their zero values, along with the values to write to them.
+func insertedColumns(value reflect.Value, columns []structColumn) ([]structColumn, []any) {
+	var inserted []structColumn
+	var args []any
+	for _, col := range columns {
+		if col.readOnly {
+			continue
+		}
+		fv, ok := fieldByIndexNoAlloc(value, col.index)
+		if col.omitEmpty && (!ok || fv.IsZero()) {
+			continue
+		}
+		inserted = append(inserted, col)
+		if ok {
+			args = append(args, fv.Interface())
+		} else {
+			args = append(args, nil)
+		}
+	}
+	return inserted, args
+}
+
+// writeInsert writes an INSERT into table of columns, whose values are
+// arguments $1 onward.
+func writeInsert(sb *strings.Builder, table string, columns []structColumn) {
+	sb.WriteString("insert into ")
+	sb.WriteString(sanitizeTableName(table))
+	if len(columns) == 0 {
+		sb.WriteString(" default values")
+		return
+	}
+	sb.WriteString(" (")
+	for i, col := range columns {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(pgx.Identifier{col.name}.Sanitize())
+	}
+	sb.WriteString(") values (")
+	for i := range columns {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		fmt.Fprintf(sb, "$%d", i+1)
+	}
+	sb.WriteString(")")
+}
+
+// buildInsert returns the INSERT statement, and its arguments, for writing
+// value to table. If returning is set, the statement returns every column
+// of value's struct type, including readonly ones, so that the whole struct
+// can be read back.
+func buildInsert(table string, value reflect.Value, columns []structColumn, returning bool) (string, []any) {
+	inserted, args := insertedColumns(value, columns)
+	var sb strings.Builder
+	writeInsert(&sb, table, inserted)
+	if returning {
+		writeReturning(&sb, columns)
+	}
+	return sb.String(), args
+}
+
+// writeReturning writes a RETURNING clause for every one of columns.
+func writeReturning(sb *strings.Builder, columns []structColumn) {
+	sb.WriteString(" returning ")
+	for i, col := range columns {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(pgx.Identifier{col.name}.Sanitize())
+	}
+}
+
+// fieldByIndexCopy is like fieldByIndexAlloc, except that the first time
+// it comes across a non-nil pointer to an embedded struct, it points it at
+// a copy of that struct, so that setting the field doesn't change a struct
+// that is shared with whatever v was copied from. copied holds the pointers
+// that already point at copies.
+func fieldByIndexCopy(v reflect.Value, index []int, copied map[uintptr]bool) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() || !copied[v.Pointer()] {
+				p := reflect.New(v.Type().Elem())
+				if !v.IsNil() {
+					p.Elem().Set(v.Elem())
+				}
+				v.Set(p)
+				copied[p.Pointer()] = true
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// scanReturning scans the single row returned by rows into a copy of value.
+// Embedded structs that value points to are copied too, so that value itself
+// is left as it was.
+func scanReturning[T any](rows pgx.Rows, value T, columns []structColumn) (T, error) {
+	return CollectExactlyOneRow(rows, func(row pgx.CollectableRow) (T, error) {
+		dstElemValue := reflect.ValueOf(&value).Elem()
+		copied := make(map[uintptr]bool)
+		scanTargets := make([]any, len(columns))
+		for i, col := range columns {
+			scanTargets[i] = fieldByIndexCopy(dstElemValue, col.index, copied).Addr().Interface()
+		}
+		err := row.Scan(scanTargets...)
+		return value, err
+	})
+}
+
+// Insert inserts value, which must be a struct, into table as a new row.
+// Each field of value is written to a column, following the same rules as
+// the struct scanning functions: a "db" tag gives the column name, `db:"-"`
+// skips the field, and the fields of embedded structs (and of nested structs
+// tagged with the "inline" or "prefix=" options) are treated as fields of
+// value. A field without a name in a "db" tag is written to the column named
+// CamelToSnake of the field name, so the field UserID goes to the column user_id.
+//
+// Two more "db" tag options say what not to write. A field tagged with the
+// "omitempty" option is left out when it has its zero value, so that the
+// column gets its default. A field tagged with the "readonly" option, such as
+// a serial ID or a generated column, is never written. For instance,
+//
+//	type User struct {
+//		ID        int64     `db:"id,readonly"`
+//		Name      string
+//		CreatedAt time.Time `db:",omitempty"`
+//	}
+//
+//	err := pgxtras.Insert(ctx, conn, "users", User{Name: "Joe"})
+//
+// runs "insert into "users" ("name") values ($1)". table may be qualified
+// with a schema name, as in "public.users"; both parts are quoted.
+func Insert[T any](ctx context.Context, db Execer, table string, value T) error {
+	dstElemValue := reflect.ValueOf(&value).Elem()
+	columns, err := structColumns(dstElemValue.Type())
+	if err != nil {
+		return err
+	}
+	sql, args := buildInsert(table, dstElemValue, columns, false)
+	_, err = db.Exec(ctx, sql, args...)
+	return err
+}
+
+// InsertReturning is Insert, except that it returns value as it was inserted,
+// with every field, including readonly ones (such as a serial ID) and those
+// left out by omitempty, read back from the new row:
+//
+//	user, err := pgxtras.InsertReturning(ctx, conn, "users", User{Name: "Joe"})
+//	// user.ID and user.CreatedAt are now set
+//
+// If value embeds a pointer to a struct, the returned value points to a copy
+// of that struct, so the one that value points to isn't changed. (Embedded
+// pointers to structs of unexported types are ignored altogether, as they are
+// by Insert and the struct scanning functions: their fields are neither
+// inserted nor read back.)
+func InsertReturning[T any](ctx context.Context, db Querier, table string, value T) (T, error) {
+	dstElemValue := reflect.ValueOf(&value).Elem()
+	columns, err := structColumns(dstElemValue.Type())
+	if err != nil {
+		return value, err
+	}
+	sql, args := buildInsert(table, dstElemValue, columns, true)
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return value, err
+	}
+	return scanReturning(rows, value, columns)
+}
@@ -0,0 +1,106 @@
+package pgxtras_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/manniwood/pgxtras"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type insertAudit struct {
+	CreatedAt time.Time `db:",omitempty"`
+}
+
+type insertUser struct {
+	ID int32 `db:"id,readonly"`
+	insertAudit
+	Name     string
+	Email    *string
+	Nickname string `db:",omitempty"`
+	Scratch  string `db:"-"`
+}
+
+func TestInsert(t *testing.T) {
+	runWithUsersTable(t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		err := pgxtras.Insert(ctx, conn, "users", insertUser{ID: 99, Name: "Joe", Scratch: "ignored"})
+		require.NoError(t, err)
+
+		users := readUsers[insertUser](t, ctx, conn)
+		require.Len(t, users, 1)
+		assert.Equal(t, int32(1), users[0].ID)
+		assert.Equal(t, "Joe", users[0].Name)
+		assert.Nil(t, users[0].Email)
+		assert.Equal(t, "anon", users[0].Nickname)
+		assert.Equal(t, 2020, users[0].CreatedAt.UTC().Year())
+	})
+}
+
+func TestInsertReturning(t *testing.T) {
+	runWithUsersTable(t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		email := "joe@example.com"
+		user, err := pgxtras.InsertReturning(ctx, conn, "users", insertUser{Name: "Joe", Email: &email, Nickname: "jj"})
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), user.ID)
+		assert.Equal(t, "Joe", user.Name)
+		require.NotNil(t, user.Email)
+		assert.Equal(t, email, *user.Email)
+		assert.Equal(t, "jj", user.Nickname)
+		assert.Equal(t, 2020, user.CreatedAt.UTC().Year())
+
+		user, err = pgxtras.InsertReturning(ctx, conn, "pg_temp.users", insertUser{Name: "Ann"})
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), user.ID)
+		assert.Equal(t, "anon", user.Nickname)
+	})
+}
+
+func TestInsertReturningEmbeddedPointer(t *testing.T) {
+	type Audit struct {
+		CreatedAt time.Time `db:",omitempty"`
+	}
+	type auditedUser struct {
+		ID int32 `db:"id,readonly"`
+		*Audit
+		Name string
+	}
+
+	runWithUsersTable(t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		audit := &Audit{}
+		value := auditedUser{Audit: audit, Name: "Joe"}
+		user, err := pgxtras.InsertReturning(ctx, conn, "users", value)
+		require.NoError(t, err)
+		assert.Equal(t, 2020, user.CreatedAt.UTC().Year())
+
+		// the caller's embedded struct isn't filled in behind its back
+		assert.True(t, audit.CreatedAt.IsZero())
+		assert.Same(t, audit, value.Audit)
+		assert.NotSame(t, audit, user.Audit)
+	})
+}
+
+func TestInsertDefaultValues(t *testing.T) {
+	type counter struct {
+		ID    int32 `db:"id,readonly"`
+		Count int32 `db:"count,omitempty"`
+	}
+
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		_, err := conn.Exec(ctx, `create temporary table counters (id serial primary key, count int4 not null default 7)`)
+		require.NoError(t, err)
+
+		c, err := pgxtras.InsertReturning(ctx, conn, "counters", counter{})
+		require.NoError(t, err)
+		assert.Equal(t, counter{ID: 1, Count: 7}, c)
+	})
+}
+
+func TestInsertNotAStruct(t *testing.T) {
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		err := pgxtras.Insert(ctx, conn, "users", 42)
+		assert.EqualError(t, err, "value must be a struct, but is int")
+	})
+}
@@ -0,0 +1,222 @@
+package pgxtras
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// maxBindParameters is the most parameters that Postgres allows in one
+// statement, since the number of them is sent as an int16.
+const maxBindParameters = 65535
+
+// InsertManyOption adjusts how InsertMany inserts rows.
+type InsertManyOption func(*insertManyConfig)
+
+type insertManyConfig struct {
+	maxRows   int
+	inBatch   bool
+	doNothing bool
+	upsert    *upsertConfig
+}
+
+// MaxRowsPerInsert sets the most rows that InsertMany puts in one INSERT
+// statement. However it's set, InsertMany never puts so many rows in a
+// statement that it has more than 65535 parameters, which is the most that
+// Postgres allows.
+func MaxRowsPerInsert(n int) InsertManyOption {
+	return func(c *insertManyConfig) {
+		c.maxRows = n
+	}
+}
+
+// InBatch makes InsertMany send all of its INSERT statements at once, in a
+// pgx.Batch, rather than one at a time. Postgres runs a batch in a transaction
+// of its own, if it isn't already in one, so either every row is inserted or
+// none are. The Execer given to InsertMany must then also be a Batcher.
+func InBatch() InsertManyOption {
+	return func(c *insertManyConfig) {
+		c.inBatch = true
+	}
+}
+
+// OnConflictDoNothing makes InsertMany skip rows that conflict with existing
+// rows, as in "on conflict do nothing".
+func OnConflictDoNothing() InsertManyOption {
+	return func(c *insertManyConfig) {
+		c.doNothing = true
+		c.upsert = nil
+	}
+}
+
+// OnConflictUpdate makes InsertMany update the existing rows that rows
+// conflict with, in the same way that Upsert does, given the same options,
+// except that "omitempty" columns are never updated. Every row has the same
+// columns, so a row whose omitempty field is empty is inserted with DEFAULT
+// for it, and updating the column from that would reset the existing row's
+// value to the default, where Upsert would leave it alone.
+func OnConflictUpdate(opts ...UpsertOption) InsertManyOption {
+	return func(c *insertManyConfig) {
+		c.doNothing = false
+		c.upsert = &upsertConfig{}
+		for _, opt := range opts {
+			opt(c.upsert)
+		}
+	}
+}
+
+// buildInsertMany returns the INSERT statement, and its arguments, for
+// writing values to table. Every row has the same columns, so an omitempty
+// field with its zero value is written as DEFAULT.
+func buildInsertMany(table string, values reflect.Value, columns []structColumn, c *insertManyConfig) (string, []any) {
+	var sb strings.Builder
+	sb.WriteString("insert into ")
+	sb.WriteString(sanitizeTableName(table))
+	sb.WriteString(" (")
+	for i, col := range columns {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(pgx.Identifier{col.name}.Sanitize())
+	}
+	sb.WriteString(") values ")
+
+	var args []any
+	for i := 0; i < values.Len(); i++ {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString("(")
+		value := values.Index(i)
+		for j, col := range columns {
+			if j > 0 {
+				sb.WriteString(", ")
+			}
+			fv, ok := fieldByIndexNoAlloc(value, col.index)
+			if col.omitEmpty && (!ok || fv.IsZero()) {
+				sb.WriteString("default")
+				continue
+			}
+			if ok {
+				args = append(args, fv.Interface())
+			} else {
+				args = append(args, nil)
+			}
+			fmt.Fprintf(&sb, "$%d", len(args))
+		}
+		sb.WriteString(")")
+	}
+
+	switch {
+	case c.upsert != nil:
+		// omitempty columns aren't updated, as explained by OnConflictUpdate.
+		var updatable []structColumn
+		for _, col := range columns {
+			if !col.omitEmpty {
+				updatable = append(updatable, col)
+			}
+		}
+		c.upsert.writeOnConflict(&sb, updatable)
+	case c.doNothing:
+		sb.WriteString(" on conflict do nothing")
+	}
+	return sb.String(), args
+}
+
+// InsertMany inserts values, which must be structs, into table as new rows,
+// writing fields to columns following the same rules as Insert, and returns
+// how many rows were inserted. It puts as many rows as it can into each
+// INSERT statement, which is much quicker than inserting them one at a time,
+// though not as quick as CopyStructs. Use it instead of CopyStructs when
+// COPY won't do, such as when rows may conflict with existing ones:
+//
+//	n, err := pgxtras.InsertMany(ctx, conn, "users", users,
+//		pgxtras.OnConflictUpdate(pgxtras.ConflictColumns("email")))
+//
+// Every row has the same columns, so an "omitempty" field with its zero value
+// is inserted as DEFAULT, and omitempty columns aren't updated by
+// OnConflictUpdate. Rows that are upserted with OnConflictUpdate must
+// not conflict with one another, since Postgres won't update the same row
+// twice in one statement.
+//
+// Unless the InBatch option is given, the INSERT statements are run one at a
+// time, so if one fails, the rows inserted by those before it stay inserted,
+// unless db is a transaction.
+func InsertMany[T any](ctx context.Context, db Execer, table string, values []T, opts ...InsertManyOption) (int64, error) {
+	var c insertManyConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	structType := reflect.TypeOf((*T)(nil)).Elem()
+	allColumns, err := structColumns(structType)
+	if err != nil {
+		return 0, err
+	}
+	var columns []structColumn
+	for _, col := range allColumns {
+		if !col.readOnly {
+			columns = append(columns, col)
+		}
+	}
+	if len(columns) == 0 {
+		return 0, fmt.Errorf("struct %s has no columns to insert", structType)
+	}
+	if c.upsert != nil {
+		if err := c.upsert.validate(structType, allColumns); err != nil {
+			return 0, err
+		}
+	}
+	var batcher Batcher
+	if c.inBatch {
+		var ok bool
+		if batcher, ok = db.(Batcher); !ok {
+			return 0, fmt.Errorf("can't insert in a batch with %T, which has no SendBatch method", db)
+		}
+	}
+	if len(values) == 0 {
+		return 0, nil
+	}
+
+	rowsPerInsert := maxBindParameters / len(columns)
+	if c.maxRows > 0 && c.maxRows < rowsPerInsert {
+		rowsPerInsert = c.maxRows
+	}
+
+	var batch pgx.Batch
+	var inserted int64
+	rv := reflect.ValueOf(values)
+	for start := 0; start < len(values); start += rowsPerInsert {
+		end := start + rowsPerInsert
+		if end > len(values) {
+			end = len(values)
+		}
+		sql, args := buildInsertMany(table, rv.Slice(start, end), columns, &c)
+		if c.inBatch {
+			batch.Queue(sql, args...)
+			continue
+		}
+		tag, err := db.Exec(ctx, sql, args...)
+		if err != nil {
+			return inserted, err
+		}
+		inserted += tag.RowsAffected()
+	}
+	if !c.inBatch {
+		return inserted, nil
+	}
+
+	br := batcher.SendBatch(ctx, &batch)
+	for i := 0; i < batch.Len(); i++ {
+		tag, err := br.Exec()
+		if err != nil {
+			br.Close()
+			return 0, err
+		}
+		inserted += tag.RowsAffected()
+	}
+	return inserted, br.Close()
+}
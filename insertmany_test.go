@@ -0,0 +1,125 @@
+package pgxtras_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/manniwood/pgxtras"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type manyUser struct {
+	ID    int32 `db:"id,readonly"`
+	Email string
+	Name  string
+	Stock int32 `db:",omitempty"`
+}
+
+func manyUsers(n int) []manyUser {
+	users := make([]manyUser, n)
+	for i := range users {
+		users[i] = manyUser{Email: fmt.Sprintf("user%d@example.com", i), Name: fmt.Sprintf("User %d", i), Stock: int32(i % 3)}
+	}
+	return users
+}
+
+func TestInsertMany(t *testing.T) {
+	runWithUsersTable(t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		// 3 columns make for 21845 rows in each INSERT, so 50000 rows take 3.
+		n, err := pgxtras.InsertMany(ctx, conn, "users", manyUsers(50000))
+		require.NoError(t, err)
+		assert.EqualValues(t, 50000, n)
+
+		var count, defaulted int32
+		err = conn.QueryRow(ctx, `select count(*), count(*) filter (where stock = 10) from users`).Scan(&count, &defaulted)
+		require.NoError(t, err)
+		assert.Equal(t, int32(50000), count)
+		assert.Equal(t, int32(16667), defaulted)
+	})
+}
+
+func TestInsertManyInBatch(t *testing.T) {
+	runWithUsersTable(t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		n, err := pgxtras.InsertMany(ctx, conn, "users", manyUsers(25), pgxtras.InBatch(), pgxtras.MaxRowsPerInsert(10))
+		require.NoError(t, err)
+		assert.EqualValues(t, 25, n)
+
+		// The last row conflicts, so the whole batch is rolled back.
+		users := manyUsers(30)[20:]
+		users[9].Email = "user0@example.com"
+		_, err = pgxtras.InsertMany(ctx, conn, "users", users, pgxtras.InBatch(), pgxtras.MaxRowsPerInsert(5))
+		assert.True(t, pgxtras.IsUniqueViolation(err))
+
+		assert.Len(t, readUsers[manyUser](t, ctx, conn), 25)
+	})
+}
+
+func TestInsertManyOnConflict(t *testing.T) {
+	runWithUsersTable(t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		_, err := pgxtras.InsertMany(ctx, conn, "users", manyUsers(10))
+		require.NoError(t, err)
+
+		users := manyUsers(15)[5:]
+		for i := range users {
+			users[i].Name = "Renamed"
+		}
+		n, err := pgxtras.InsertMany(ctx, conn, "users", users, pgxtras.OnConflictDoNothing())
+		require.NoError(t, err)
+		assert.EqualValues(t, 5, n)
+
+		n, err = pgxtras.InsertMany(ctx, conn, "users", users,
+			pgxtras.OnConflictUpdate(pgxtras.ConflictColumns("email"), pgxtras.ExcludeFromUpdate("stock")))
+		require.NoError(t, err)
+		assert.EqualValues(t, 10, n)
+
+		renamed := 0
+		for _, user := range readUsers[manyUser](t, ctx, conn) {
+			if user.Name == "Renamed" {
+				renamed++
+			}
+		}
+		assert.Equal(t, 10, renamed)
+	})
+}
+
+func TestInsertManyOnConflictOmitEmpty(t *testing.T) {
+	runWithUsersTable(t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		// stocks of 10 (the default), 1 and 2
+		_, err := pgxtras.InsertMany(ctx, conn, "users", manyUsers(3))
+		require.NoError(t, err)
+
+		users := manyUsers(3)
+		for i := range users {
+			users[i].Name = "Renamed"
+			users[i].Stock = 0
+		}
+		n, err := pgxtras.InsertMany(ctx, conn, "users", users,
+			pgxtras.OnConflictUpdate(pgxtras.ConflictColumns("email")))
+		require.NoError(t, err)
+		assert.EqualValues(t, 3, n)
+
+		// the empty stocks were inserted as DEFAULT, but don't reset the existing ones
+		var names []string
+		var stocks []int32
+		for _, user := range readUsers[manyUser](t, ctx, conn) {
+			names = append(names, user.Name)
+			stocks = append(stocks, user.Stock)
+		}
+		assert.Equal(t, []string{"Renamed", "Renamed", "Renamed"}, names)
+		assert.Equal(t, []int32{10, 1, 2}, stocks)
+	})
+}
+
+func TestInsertManyErrors(t *testing.T) {
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		n, err := pgxtras.InsertMany(ctx, conn, "users", []manyUser{})
+		assert.NoError(t, err)
+		assert.EqualValues(t, 0, n)
+
+		_, err = pgxtras.InsertMany(ctx, conn, "users", manyUsers(1), pgxtras.OnConflictUpdate())
+		assert.EqualError(t, err, "no conflict target to upsert on; use ConflictColumns or ConflictConstraint")
+	})
+}
@@ -60,3 +60,61 @@ type QuerierExecer interface {
 	Querier
 	Execer
 }
+
+// QueryRower is an interface that wraps the QueryRow method used by
+// pgx.Conn, pgxpool.Pool, pgx.Tx, pgxpool.Tx, and pgxpool.Conn.
+//
+// Feel free to copy this interface directly into your code
+// instead of importing this module just to use this interface:
+// The licence is quite permissive.
+type QueryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Batcher is an interface that wraps the SendBatch method used by
+// pgx.Conn, pgxpool.Pool, pgx.Tx, pgxpool.Tx, and pgxpool.Conn.
+//
+// Feel free to copy this interface directly into your code
+// instead of importing this module just to use this interface:
+// The licence is quite permissive.
+type Batcher interface {
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+// CopyFromer is an interface that wraps the CopyFrom method used by
+// pgx.Conn, pgxpool.Pool, pgx.Tx, pgxpool.Tx, and pgxpool.Conn.
+//
+// Feel free to copy this interface directly into your code
+// instead of importing this module just to use this interface:
+// The licence is quite permissive.
+type CopyFromer interface {
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+// Beginner is an interface that wraps the Begin method used by
+// pgx.Conn, pgxpool.Pool, pgx.Tx, pgxpool.Tx, and pgxpool.Conn.
+// Begin on a pgx.Tx or pgxpool.Tx starts a pseudo nested transaction
+// using a savepoint.
+//
+// Feel free to copy this interface directly into your code
+// instead of importing this module just to use this interface:
+// The licence is quite permissive.
+type Beginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// DB combines all of the interfaces above, for functions and methods
+// that need more than one or two of them. It is satisfied by pgx.Conn,
+// pgxpool.Pool, pgx.Tx, pgxpool.Tx, and pgxpool.Conn.
+//
+// Feel free to copy this interface directly into your code
+// instead of importing this module just to use this interface:
+// The licence is quite permissive.
+type DB interface {
+	Querier
+	Execer
+	QueryRower
+	Batcher
+	CopyFromer
+	Beginner
+}
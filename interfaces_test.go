@@ -0,0 +1,22 @@
+package pgxtras_test
+
+import (
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/manniwood/pgxtras"
+)
+
+// Compile-time checks that the concrete pgx types satisfy the interfaces.
+var (
+	_ pgxtras.DB = (*pgx.Conn)(nil)
+	_ pgxtras.DB = (*pgxpool.Pool)(nil)
+	_ pgxtras.DB = (pgx.Tx)(nil)
+	_ pgxtras.DB = (*pgxpool.Tx)(nil)
+	_ pgxtras.DB = (*pgxpool.Conn)(nil)
+
+	_ pgxtras.QuerierExecer = (*pgx.Conn)(nil)
+	_ pgxtras.QuerierExecer = (*pgxpool.Pool)(nil)
+	_ pgxtras.QuerierExecer = (pgx.Tx)(nil)
+	_ pgxtras.QuerierExecer = (*pgxpool.Tx)(nil)
+	_ pgxtras.QuerierExecer = (*pgxpool.Conn)(nil)
+)
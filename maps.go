@@ -0,0 +1,274 @@
+package pgxtras
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// FormatOptions says how RowToMapStrStrWith formats column values as strings.
+// The zero value formats NULL as the empty string and times as RFC 3339.
+type FormatOptions struct {
+	// Null is what NULL is formatted as.
+	Null string
+	// TimeLayout is the layout, as understood by time.Time.Format, of
+	// timestamps and dates. If empty, time.RFC3339Nano is used.
+	TimeLayout string
+}
+
+// RowToMapStrStrWith returns a RowToFunc that scans a row into a map from
+// column names to values formatted as strings according to opts. Unlike
+// RowToMapStrStr, it formats NULL as opts.Null rather than as "<nil>",
+// bytea as hex in the style of psql (\x0102), uuids in their usual
+// hyphenated form, and anything (such as a numeric) that pgx represents
+// as a driver.Valuer as the value it gives. Anything else is formatted
+// with fmt.Sprintf("%v").
+func RowToMapStrStrWith(opts FormatOptions) pgx.RowToFunc[map[string]string] {
+	if opts.TimeLayout == "" {
+		opts.TimeLayout = time.RFC3339Nano
+	}
+	return func(row pgx.CollectableRow) (map[string]string, error) {
+		value := mapStrStrWithRowScanner{opts: opts}
+		err := row.Scan(&value)
+		return value.m, err
+	}
+}
+
+type mapStrStrWithRowScanner struct {
+	opts FormatOptions
+	m    map[string]string
+}
+
+func (rs *mapStrStrWithRowScanner) ScanRow(rows pgx.Rows) error {
+	values, err := rows.Values()
+	if err != nil {
+		return err
+	}
+
+	rs.m = make(map[string]string, len(values))
+	for i := range values {
+		str, err := rs.opts.format(values[i])
+		if err != nil {
+			return fmt.Errorf("can't format returned column %s: %w", rows.FieldDescriptions()[i].Name, err)
+		}
+		rs.m[rows.FieldDescriptions()[i].Name] = str
+	}
+
+	return nil
+}
+
+func (opts FormatOptions) format(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return opts.Null, nil
+	case string:
+		return v, nil
+	case []byte:
+		return `\x` + hex.EncodeToString(v), nil
+	case time.Time:
+		return v.Format(opts.TimeLayout), nil
+	case [16]byte:
+		return fmt.Sprintf("%x-%x-%x-%x-%x", v[0:4], v[4:6], v[6:8], v[8:10], v[10:16]), nil
+	case driver.Valuer:
+		dv, err := v.Value()
+		if err != nil {
+			return "", err
+		}
+		return opts.format(dv)
+	default:
+		return fmt.Sprintf("%v", v), nil
+	}
+}
+
+// RowToMapStrText returns a map scanned from row, from column names to their
+// values in text format. NULL becomes the empty string; use RowToMapStrPtrStr
+// to tell NULL from an empty string.
+//
+// Values that Postgres sent in text format are returned just as it sent them,
+// which is what psql shows. But pgx asks for many types, such as timestamptz,
+// interval, numeric and float8, in binary format, and those values are decoded
+// and then encoded as text by pgx, using the type map of the connection that
+// returned row. pgx's text isn't always Postgres's: a timestamptz is shown in
+// UTC, as 2023-01-02 03:04:05.5Z rather than 2023-01-02 03:04:05.5+00 or
+// whatever the session time zone makes it, an interval as 1 day 02:00:00.000000
+// rather than 1 day 02:00:00, and a float8 of 1e+20 as 100000000000000000000.
+// To get exactly what psql shows, have Postgres send every column as text by
+// passing pgx.QueryResultFormats{pgx.TextFormatCode} as the first argument to
+// Query:
+//
+//	rows, err := conn.Query(ctx, sql, pgx.QueryResultFormats{pgx.TextFormatCode}, args...)
+func RowToMapStrText(row pgx.CollectableRow) (map[string]string, error) {
+	var value map[string]string
+	err := row.Scan((*mapStrTextRowScanner)(&value))
+	return value, err
+}
+
+type mapStrTextRowScanner map[string]string
+
+func (rs *mapStrTextRowScanner) ScanRow(rows pgx.Rows) error {
+	*rs = make(mapStrTextRowScanner, len(rows.RawValues()))
+	return scanText(rows, func(name string, text []byte) {
+		(*rs)[name] = string(text)
+	})
+}
+
+// RowToMapStrPtrStr is RowToMapStrText, except that a NULL becomes a nil
+// pointer, so that it can be told apart from an empty string.
+func RowToMapStrPtrStr(row pgx.CollectableRow) (map[string]*string, error) {
+	var value map[string]*string
+	err := row.Scan((*mapStrPtrStrRowScanner)(&value))
+	return value, err
+}
+
+type mapStrPtrStrRowScanner map[string]*string
+
+func (rs *mapStrPtrStrRowScanner) ScanRow(rows pgx.Rows) error {
+	*rs = make(mapStrPtrStrRowScanner, len(rows.RawValues()))
+	return scanText(rows, func(name string, text []byte) {
+		if text == nil {
+			(*rs)[name] = nil
+			return
+		}
+		str := string(text)
+		(*rs)[name] = &str
+	})
+}
+
+// scanText calls fn with the name and text format value of each column of
+// the current row, or a nil value for NULL.
+func scanText(rows pgx.Rows, fn func(name string, text []byte)) error {
+	fldDescs := rows.FieldDescriptions()
+	for i := range rows.RawValues() {
+		text, err := textValue(rows, i)
+		if err != nil {
+			return fmt.Errorf("can't format returned column %s: %w", fldDescs[i].Name, err)
+		}
+		fn(fldDescs[i].Name, text)
+	}
+	return nil
+}
+
+// textValue returns the text format value of column i of the current row,
+// or nil for NULL. Values that pgx received in binary format are converted
+// using the type map of the connection that returned rows.
+func textValue(rows pgx.Rows, i int) ([]byte, error) {
+	raw := rows.RawValues()[i]
+	fd := rows.FieldDescriptions()[i]
+	if raw == nil || fd.Format == pgtype.TextFormatCode {
+		return raw, nil
+	}
+
+	var typeMap *pgtype.Map
+	if conn := rows.Conn(); conn != nil {
+		typeMap = conn.TypeMap()
+	} else {
+		typeMap = pgtype.NewMap()
+	}
+	return binaryToText(typeMap, fd.DataTypeOID, raw)
+}
+
+// binaryToText converts a binary format value of type oid to text format by
+// decoding it and encoding it again.
+func binaryToText(typeMap *pgtype.Map, oid uint32, raw []byte) ([]byte, error) {
+	typ, ok := typeMap.TypeForOID(oid)
+	if !ok {
+		return nil, fmt.Errorf("unknown type OID %d in binary format", oid)
+	}
+
+	// pgtype can decode any array, but can only encode arrays of
+	// particular Go types, so arrays are put together here.
+	if arrayCodec, ok := typ.Codec.(*pgtype.ArrayCodec); ok {
+		var arr pgtype.Array[any]
+		err := typeMap.Scan(oid, pgtype.BinaryFormatCode, raw, &arr)
+		if err != nil {
+			return nil, err
+		}
+		return appendArrayText(typeMap, arrayCodec.ElementType.OID, arr, []byte{})
+	}
+
+	value, err := typ.Codec.DecodeValue(typeMap, oid, pgtype.BinaryFormatCode, raw)
+	if err != nil {
+		return nil, err
+	}
+	return typeMap.Encode(oid, pgtype.TextFormatCode, value, []byte{})
+}
+
+// appendArrayText appends arr to buf in the text format of an array
+// literal, such as {{1,2},{3,NULL}}.
+func appendArrayText(typeMap *pgtype.Map, elemOID uint32, arr pgtype.Array[any], buf []byte) ([]byte, error) {
+	if len(arr.Dims) == 0 {
+		return append(buf, "{}"...), nil
+	}
+
+	for _, dim := range arr.Dims {
+		if dim.LowerBound != 1 {
+			for _, dim := range arr.Dims {
+				buf = append(buf, fmt.Sprintf("[%d:%d]", dim.LowerBound, dim.LowerBound+dim.Length-1)...)
+			}
+			buf = append(buf, '=')
+			break
+		}
+	}
+
+	elems := arr.Elements
+	var appendDim func(d int) error
+	appendDim = func(d int) error {
+		buf = append(buf, '{')
+		for i := 0; i < int(arr.Dims[d].Length); i++ {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			if d+1 < len(arr.Dims) {
+				if err := appendDim(d + 1); err != nil {
+					return err
+				}
+				continue
+			}
+
+			elem := elems[0]
+			elems = elems[1:]
+			if elem == nil {
+				buf = append(buf, "NULL"...)
+				continue
+			}
+			text, err := typeMap.Encode(elemOID, pgtype.TextFormatCode, elem, []byte{})
+			if err != nil {
+				return err
+			}
+			buf = appendArrayElemText(buf, text)
+		}
+		buf = append(buf, '}')
+		return nil
+	}
+	err := appendDim(0)
+	return buf, err
+}
+
+// appendArrayElemText appends an array element to buf, quoting it
+// if Postgres would.
+func appendArrayElemText(buf []byte, text []byte) []byte {
+	quote := len(text) == 0 || strings.EqualFold(string(text), "NULL")
+	for _, b := range text {
+		switch b {
+		case '{', '}', ',', '"', '\\', ' ', '\t', '\n', '\r', '\v', '\f':
+			quote = true
+		}
+	}
+	if !quote {
+		return append(buf, text...)
+	}
+
+	buf = append(buf, '"')
+	for _, b := range text {
+		if b == '"' || b == '\\' {
+			buf = append(buf, '\\')
+		}
+		buf = append(buf, b)
+	}
+	return append(buf, '"')
+}
@@ -0,0 +1,100 @@
+package pgxtras_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/manniwood/pgxtras"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mapsTestQuery = `
+select 42 as id,
+       123.4500::numeric as amount,
+       '2023-01-02 03:04:05.5+00'::timestamptz as created_at,
+       '\x0102ff'::bytea as data,
+       'a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11'::uuid as uuid,
+       null::text as nickname,
+       ''::text as note,
+       '2023-01-02'::date as birthday,
+       array[1, 2, null]::int4[] as scores,
+       array['a b', '', null, 'x"y\z', 'plain']::text[] as tags,
+       array[[1, 2], [3, 4]]::int4[] as grid,
+       '1 day 2 hours'::interval as duration,
+       '{"b": 1, "a": [1, 2]}'::jsonb as doc`
+
+func TestRowToMapStrStrWith(t *testing.T) {
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		rows, _ := conn.Query(ctx, mapsTestQuery)
+		m, err := pgx.CollectOneRow(rows, pgxtras.RowToMapStrStrWith(pgxtras.FormatOptions{Null: "NULL", TimeLayout: "2006-01-02"}))
+		require.NoError(t, err)
+
+		assert.Equal(t, "42", m["id"])
+		assert.Equal(t, "123.4500", m["amount"])
+		assert.Equal(t, "2023-01-02", m["created_at"])
+		assert.Equal(t, `\x0102ff`, m["data"])
+		assert.Equal(t, "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11", m["uuid"])
+		assert.Equal(t, "NULL", m["nickname"])
+		assert.Equal(t, "", m["note"])
+
+		rows, _ = conn.Query(ctx, `select '2023-01-02 03:04:05.5+00'::timestamptz as created_at`)
+		m, err = pgx.CollectOneRow(rows, pgxtras.RowToMapStrStrWith(pgxtras.FormatOptions{}))
+		require.NoError(t, err)
+		ts, err := time.Parse(time.RFC3339Nano, m["created_at"])
+		require.NoError(t, err)
+		assert.True(t, ts.Equal(time.Date(2023, 1, 2, 3, 4, 5, 500000000, time.UTC)))
+	})
+}
+
+func TestRowToMapStrText(t *testing.T) {
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		rows, _ := conn.Query(ctx, mapsTestQuery)
+		m, err := pgx.CollectOneRow(rows, pgxtras.RowToMapStrText)
+		require.NoError(t, err)
+
+		assert.Equal(t, "42", m["id"])
+		assert.Equal(t, "123.4500", m["amount"])
+		assert.Equal(t, `\x0102ff`, m["data"])
+		assert.Equal(t, "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11", m["uuid"])
+		assert.Equal(t, "", m["nickname"])
+		assert.Equal(t, "", m["note"])
+		assert.Equal(t, "2023-01-02", m["birthday"])
+		assert.Equal(t, "{1,2,NULL}", m["scores"])
+		assert.Equal(t, `{"a b","",NULL,"x\"y\\z",plain}`, m["tags"])
+		assert.Equal(t, "{{1,2},{3,4}}", m["grid"])
+		assert.Equal(t, `{"a": [1, 2], "b": 1}`, m["doc"])
+
+		// pgx asks for these in binary, and its text for them isn't psql's
+		assert.Equal(t, "2023-01-02 03:04:05.5Z", m["created_at"])
+		assert.Equal(t, "1 day 02:00:00.000000", m["duration"])
+
+		// unless everything is asked for in text
+		_, err = conn.Exec(ctx, `set timezone = 'UTC'`)
+		require.NoError(t, err)
+		rows, _ = conn.Query(ctx, mapsTestQuery, pgx.QueryResultFormats{pgx.TextFormatCode})
+		m, err = pgx.CollectOneRow(rows, pgxtras.RowToMapStrText)
+		require.NoError(t, err)
+		assert.Equal(t, "123.4500", m["amount"])
+		assert.Equal(t, "2023-01-02 03:04:05.5+00", m["created_at"])
+		assert.Equal(t, "1 day 02:00:00", m["duration"])
+		assert.Equal(t, `{"a": [1, 2], "b": 1}`, m["doc"])
+		assert.Equal(t, "{{1,2},{3,4}}", m["grid"])
+	})
+}
+
+func TestRowToMapStrPtrStr(t *testing.T) {
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		rows, _ := conn.Query(ctx, mapsTestQuery)
+		m, err := pgx.CollectOneRow(rows, pgxtras.RowToMapStrPtrStr)
+		require.NoError(t, err)
+
+		require.NotNil(t, m["id"])
+		assert.Equal(t, "42", *m["id"])
+		assert.Nil(t, m["nickname"])
+		require.NotNil(t, m["note"])
+		assert.Equal(t, "", *m["note"])
+	})
+}
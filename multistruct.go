@@ -0,0 +1,174 @@
+package pgxtras
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ColumnSplit says which of the columns of a returned row are scanned into
+// which of several structs, such as when each row of a JOIN is scanned into
+// one struct per joined table. Make one with SplitByPrefix or SplitByPosition.
+type ColumnSplit struct {
+	prefixes  []string
+	positions []int
+}
+
+// SplitByPrefix splits the columns of a row by the prefix of their names: a
+// column whose name starts with prefixes[i] (compared case-insensitively) is
+// scanned into the i'th struct, and the prefix is removed from the column name
+// before matching it to a struct field. If a column name starts with more than
+// one of the prefixes, the longest one wins.
+//
+// For instance, SplitByPrefix("u_", "o_") sends u_id and u_name to the fields ID
+// and Name of the first struct, and o_id and o_name to the fields ID and Name
+// of the second.
+func SplitByPrefix(prefixes ...string) ColumnSplit {
+	return ColumnSplit{prefixes: prefixes}
+}
+
+// SplitByPosition splits the columns of a row by their positions: positions
+// are the (zero-based) positions of the first columns of the second and
+// subsequent structs. For instance, SplitByPosition(3, 5) sends columns 0
+// through 2 to the first struct, 3 and 4 to the second, and the rest to the
+// third.
+func SplitByPosition(positions ...int) ColumnSplit {
+	return ColumnSplit{positions: positions}
+}
+
+// numStructs returns how many structs the split splits columns between.
+func (s ColumnSplit) numStructs() int {
+	if s.prefixes != nil {
+		return len(s.prefixes)
+	}
+	return len(s.positions) + 1
+}
+
+// prefix returns the column name prefix of the pos'th struct.
+func (s ColumnSplit) prefix(pos int) string {
+	if s.prefixes != nil {
+		return s.prefixes[pos]
+	}
+	return ""
+}
+
+// structPos returns, for each column, the position of the struct that it is
+// scanned into, or -1 for a column that belongs to none of the structs.
+func (s ColumnSplit) structPos(fldDescs []pgconn.FieldDescription) []int {
+	structPos := make([]int, len(fldDescs))
+	for i := range fldDescs {
+		structPos[i] = -1
+		if s.prefixes != nil {
+			longest := -1
+			for pos, prefix := range s.prefixes {
+				if _, ok := trimPrefixFold(fldDescs[i].Name, prefix); ok && len(prefix) > longest {
+					structPos[i] = pos
+					longest = len(prefix)
+				}
+			}
+			continue
+		}
+		structPos[i] = 0
+		for _, position := range s.positions {
+			if i >= position {
+				structPos[i]++
+			}
+		}
+	}
+	return structPos
+}
+
+// MultiStructScanner scans each row into several structs at once, sending
+// each column to one of the structs according to a ColumnSplit and then
+// matching it to a field of that struct by name, just as RowToStructWith does.
+// A MultiStructScanner is safe for concurrent use, and remembers how it
+// matched columns to fields, so make one and reuse it.
+type MultiStructScanner struct {
+	split ColumnSplit
+	plans *structScanPlanCache
+}
+
+// NewMultiStructScanner returns a MultiStructScanner that splits columns
+// between structs using split and matches columns to fields using mapper.
+func NewMultiStructScanner(mapper NameMapper, split ColumnSplit, opts ...StructScanOption) *MultiStructScanner {
+	return &MultiStructScanner{split: split, plans: newStructScanPlanCache(mapper, opts...)}
+}
+
+// Scan scans row into the structs pointed to by ptrsToStructs. There must be
+// one pointer per struct that the scanner's ColumnSplit splits columns between.
+//
+// A RowToFunc for a struct holding the structs of a JOIN might look like this:
+//
+//	var userOrgScanner = pgxtras.NewMultiStructScanner(pgxtras.SimpleNameMapper, pgxtras.SplitByPrefix("u_", "o_"))
+//
+//	func rowToUserOrg(row pgx.CollectableRow) (UserOrg, error) {
+//		var uo UserOrg
+//		err := userOrgScanner.Scan(row, &uo.User, &uo.Org)
+//		return uo, err
+//	}
+func (s *MultiStructScanner) Scan(row pgx.CollectableRow, ptrsToStructs ...any) error {
+	return row.Scan(&multiStructRowScanner{ptrsToStructs: ptrsToStructs, split: s.split, plans: s.plans})
+}
+
+type multiStructRowScanner struct {
+	ptrsToStructs []any
+	split         ColumnSplit
+	plans         *structScanPlanCache
+}
+
+func (rs *multiStructRowScanner) ScanRow(rows pgx.Rows) error {
+	if len(rs.ptrsToStructs) != rs.split.numStructs() {
+		return fmt.Errorf("got %d structs to scan into, but columns are split between %d", len(rs.ptrsToStructs), rs.split.numStructs())
+	}
+
+	dstElemValues := make([]reflect.Value, len(rs.ptrsToStructs))
+	structTypes := make([]reflect.Type, len(rs.ptrsToStructs))
+	for i, dst := range rs.ptrsToStructs {
+		dstValue := reflect.ValueOf(dst)
+		if dstValue.Kind() != reflect.Ptr {
+			return &MappingError{Kind: NotAStruct, ColumnIndex: -1}
+		}
+		dstElemValues[i] = dstValue.Elem()
+		structTypes[i] = dstElemValues[i].Type()
+	}
+
+	plan, err := rs.plans.multiPlan(structTypes, rs.split, rows.FieldDescriptions())
+	if err != nil {
+		return err
+	}
+
+	err = rows.Scan(plan.scanTargets(dstElemValues...)...)
+	if err != nil {
+		return plan.wrapScanError(err, rows.FieldDescriptions())
+	}
+	return nil
+}
+
+// StructPair holds the two structs that RowToStructPair scans a row into.
+type StructPair[A, B any] struct {
+	First  A
+	Second B
+}
+
+// RowToStructPair returns a RowToFunc that scans a row into two structs, A and B,
+// splitting the row's columns between them using split and matching columns to
+// fields the same way RowToStructBySimpleName does. Every column must go to a
+// field of one of the structs, and every field of both structs must have a column.
+//
+// For instance, given the query
+//
+//	select u.id as u_id, u.name as u_name, o.id as o_id, o.name as o_name
+//	  from users u join orgs o on o.id = u.org_id
+//
+// pgx.CollectRows(rows, pgxtras.RowToStructPair[User, Org](pgxtras.SplitByPrefix("u_", "o_")))
+// returns a slice of StructPair[User, Org] with the users in First and their orgs in Second.
+func RowToStructPair[A, B any](split ColumnSplit) pgx.RowToFunc[StructPair[A, B]] {
+	scanner := NewMultiStructScanner(SimpleNameMapper, split)
+	return func(row pgx.CollectableRow) (StructPair[A, B], error) {
+		var value StructPair[A, B]
+		err := scanner.Scan(row, &value.First, &value.Second)
+		return value, err
+	}
+}
@@ -0,0 +1,99 @@
+package pgxtras_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/manniwood/pgxtras"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type multiStructUser struct {
+	ID   int32
+	Name string
+}
+
+type multiStructOrg struct {
+	ID      int32
+	Name    string
+	Country string
+}
+
+func TestRowToStructPairByPrefix(t *testing.T) {
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		rows, _ := conn.Query(ctx, `
+select n        as u_id,
+       'John'   as u_name,
+       n + 100  as o_id,
+       'Acme'   as o_name,
+       'Canada' as o_country
+  from generate_series(0, 9) n`)
+		slice, err := pgx.CollectRows(rows, pgxtras.RowToStructPair[multiStructUser, multiStructOrg](pgxtras.SplitByPrefix("u_", "o_")))
+		require.NoError(t, err)
+
+		assert.Len(t, slice, 10)
+		for i := range slice {
+			assert.Equal(t, multiStructUser{ID: int32(i), Name: "John"}, slice[i].First)
+			assert.Equal(t, multiStructOrg{ID: int32(i + 100), Name: "Acme", Country: "Canada"}, slice[i].Second)
+		}
+
+		// check a column with none of the prefixes
+		rows, _ = conn.Query(ctx, `select 1 as u_id, 'John' as u_name, 2 as o_id, 'Acme' as o_name, 'Canada' as country`)
+		_, err = pgx.CollectRows(rows, pgxtras.RowToStructPair[multiStructUser, multiStructOrg](pgxtras.SplitByPrefix("u_", "o_")))
+		assert.ErrorContains(t, err, "no column in returned row matches struct field Country")
+	})
+}
+
+func TestRowToStructPairByPosition(t *testing.T) {
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		rows, _ := conn.Query(ctx, `
+select u.id, u.name, o.id, o.name, o.country
+  from (values (1, 'John')) as u (id, name)
+  cross join (values (2, 'Acme', 'Canada')) as o (id, name, country)`)
+		pair, err := pgx.CollectOneRow(rows, pgxtras.RowToStructPair[multiStructUser, multiStructOrg](pgxtras.SplitByPosition(2)))
+		require.NoError(t, err)
+		assert.Equal(t, multiStructUser{ID: 1, Name: "John"}, pair.First)
+		assert.Equal(t, multiStructOrg{ID: 2, Name: "Acme", Country: "Canada"}, pair.Second)
+	})
+}
+
+func TestMultiStructScanner(t *testing.T) {
+	type userOrgs struct {
+		User   multiStructUser
+		Org    multiStructOrg
+		Parent multiStructOrg
+	}
+
+	scanner := pgxtras.NewMultiStructScanner(pgxtras.SimpleNameMapper, pgxtras.SplitByPrefix("u_", "o_", "p_"), pgxtras.LaxMatching())
+	rowToUserOrgs := func(row pgx.CollectableRow) (userOrgs, error) {
+		var uo userOrgs
+		err := scanner.Scan(row, &uo.User, &uo.Org, &uo.Parent)
+		return uo, err
+	}
+
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		rows, _ := conn.Query(ctx, `
+select 1        as u_id,
+       'John'   as u_name,
+       2        as o_id,
+       'Acme'   as o_name,
+       3        as p_id,
+       'Canada' as p_country`)
+		uo, err := pgx.CollectOneRow(rows, rowToUserOrgs)
+		require.NoError(t, err)
+		assert.Equal(t, multiStructUser{ID: 1, Name: "John"}, uo.User)
+		assert.Equal(t, multiStructOrg{ID: 2, Name: "Acme"}, uo.Org)
+		assert.Equal(t, multiStructOrg{ID: 3, Country: "Canada"}, uo.Parent)
+
+		// check the number of structs against the number of prefixes
+		rows, _ = conn.Query(ctx, `select 1 as u_id`)
+		_, err = pgx.CollectOneRow(rows, func(row pgx.CollectableRow) (multiStructUser, error) {
+			var u multiStructUser
+			err := scanner.Scan(row, &u)
+			return u, err
+		})
+		assert.ErrorContains(t, err, "got 1 structs to scan into, but columns are split between 3")
+	})
+}
@@ -0,0 +1,399 @@
+package pgxtras
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Named returns a pgx.QueryRewriter that lets a query use named parameters,
+// such as :user_id or @user_id, rather than $1, $2 and so on. Pass it as the
+// only argument to the Query, QueryRow or Exec method of a pgx.Conn,
+// pgxpool.Pool, pgx.Tx, or anything else that hands its arguments to pgx,
+// such as a Querier or Execer, or to pgx.Batch.Queue:
+//
+//	rows, err := conn.Query(ctx,
+//		`select * from orders where user_id = :user_id and placed_at >= :since`,
+//		pgxtras.Named(map[string]any{"user_id": 42, "since": since}))
+//
+// arg gives the values of the parameters. It may be a map with string keys,
+// such as a map[string]any or pgx.NamedArgs, whose keys are the names of the
+// parameters. It may also be a struct, or a pointer to one, in which case a
+// parameter takes its value from the field with the same "simple" name, as
+// SimpleNameMapper matches columns to fields, following the same "db" tag
+// rules as Insert; so :user_id takes its value from the field UserID. It is
+// an error for a parameter to have no value.
+//
+// See BindNamed for how named parameters are found in the query.
+func Named(arg any) pgx.QueryRewriter {
+	return namedArgs{arg: arg}
+}
+
+type namedArgs struct {
+	arg any
+}
+
+// RewriteQuery implements the pgx.QueryRewriter interface.
+func (na namedArgs) RewriteQuery(ctx context.Context, conn *pgx.Conn, sql string, args []any) (string, []any, error) {
+	if len(args) > 0 {
+		return "", nil, errors.New("named parameters can't be mixed with positional arguments")
+	}
+	return BindNamed(sql, na.arg)
+}
+
+// BindNamed rewrites sql, which uses named parameters, to use positional
+// parameters instead, and returns it along with the values of the
+// parameters, taken from arg as Named describes. It is what Named uses,
+// and is handy on its own for passing queries with named parameters to
+// something that doesn't know about pgx.QueryRewriter.
+//
+// A named parameter is a colon or at sign followed by a name made of ASCII
+// letters, digits and underscores, not starting with a digit, as in
+// :user_id or @user_id. Each name is given the same positional parameter
+// everywhere it is used. Named parameters aren't looked for in string
+// literals (including escape and dollar-quoted strings), quoted identifiers
+// or comments, and aren't confused with :: casts or with operators that
+// end in an at sign, such as <@. To keep slices such as arr[lo:hi] and
+// arr[:hi] from being taken for parameters, a colon straight after a letter,
+// digit, underscore or opening bracket doesn't start one, and nor does an at
+// sign straight after a letter, digit or underscore; so a named parameter
+// used as the lower bound of a slice, or as a subscript, must be written
+// with an at sign, as in arr[@lo:@hi] or arr[@i]. sql must not also use
+// positional parameters.
+//
+// Rewritten queries are cached, so that a query is only parsed once, however
+// often it is run. The cache holds the most recently used 1000 queries, so
+// queries built on the fly can't make it grow without limit.
+func BindNamed(sql string, arg any) (string, []any, error) {
+	stmt, err := parseNamedCached(sql)
+	if err != nil {
+		return "", nil, err
+	}
+	args, err := namedValues(stmt.names, arg)
+	if err != nil {
+		return "", nil, err
+	}
+	return stmt.sql, args, nil
+}
+
+// namedStatement is a query whose named parameters have been rewritten to
+// positional ones. names[i] is the name of parameter $i+1.
+type namedStatement struct {
+	sql   string
+	names []string
+}
+
+// namedStatementCacheSize is the most rewritten queries that are cached.
+const namedStatementCacheSize = 1000
+
+// namedStatementCache is a least recently used cache of rewritten queries,
+// keyed by the query as it was written. It is safe for concurrent use.
+type namedStatementCache struct {
+	mu       sync.Mutex
+	capacity int
+	elems    map[string]*list.Element
+	order    *list.List // of *namedStatementEntry, most recently used first
+}
+
+type namedStatementEntry struct {
+	sql  string
+	stmt *namedStatement
+}
+
+func newNamedStatementCache(capacity int) *namedStatementCache {
+	return &namedStatementCache{
+		capacity: capacity,
+		elems:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *namedStatementCache) get(sql string) (*namedStatement, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.elems[sql]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*namedStatementEntry).stmt, true
+}
+
+func (c *namedStatementCache) put(sql string, stmt *namedStatement) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.elems[sql]; ok {
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.elems[sql] = c.order.PushFront(&namedStatementEntry{sql: sql, stmt: stmt})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.elems, oldest.Value.(*namedStatementEntry).sql)
+	}
+}
+
+func (c *namedStatementCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+var namedStatements = newNamedStatementCache(namedStatementCacheSize)
+
+// parseNamedCached is parseNamed, using namedStatements to parse each query
+// only once. Queries that fail to parse aren't cached, since they are
+// mistakes to be fixed rather than queries to be run again.
+func parseNamedCached(sql string) (*namedStatement, error) {
+	if stmt, ok := namedStatements.get(sql); ok {
+		return stmt, nil
+	}
+	stmt, err := parseNamed(sql)
+	if err != nil {
+		return nil, err
+	}
+	namedStatements.put(sql, stmt)
+	return stmt, nil
+}
+
+// parseNamed rewrites the named parameters of sql to positional ones.
+func parseNamed(sql string) (*namedStatement, error) {
+	var sb strings.Builder
+	var names []string
+	ordinals := make(map[string]int)
+	start := 0 // start of the text not yet written to sb
+	i := 0
+	for i < len(sql) {
+		c := sql[i]
+		switch {
+		case c == '\'':
+			escapes := i > 0 && (sql[i-1] == 'e' || sql[i-1] == 'E') && (i == 1 || !isIdentByte(sql[i-2]))
+			end, ok := skipQuoted(sql, i, '\'', escapes)
+			if !ok {
+				return nil, errors.New("unterminated quoted string in query")
+			}
+			i = end
+		case c == '"':
+			end, ok := skipQuoted(sql, i, '"', false)
+			if !ok {
+				return nil, errors.New("unterminated quoted identifier in query")
+			}
+			i = end
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end + 1
+			}
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end, ok := skipBlockComment(sql, i)
+			if !ok {
+				return nil, errors.New("unterminated comment in query")
+			}
+			i = end
+		case c == '$' && (i == 0 || !isIdentByte(sql[i-1])):
+			if i+1 < len(sql) && isDigitByte(sql[i+1]) {
+				return nil, errors.New("named parameters can't be mixed with positional parameters")
+			}
+			end, ok, err := skipDollarQuoted(sql, i)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				end = i + 1
+			}
+			i = end
+		case c == ':' && strings.HasPrefix(sql[i:], "::"):
+			i += 2
+		case startsNamedParam(sql, i):
+			end := i + 1
+			for end < len(sql) && (isIdentStartByte(sql[end]) || isDigitByte(sql[end])) {
+				end++
+			}
+			name := sql[i+1 : end]
+			ordinal, ok := ordinals[name]
+			if !ok {
+				names = append(names, name)
+				ordinal = len(names)
+				ordinals[name] = ordinal
+			}
+			sb.WriteString(sql[start:i])
+			sb.WriteByte('$')
+			sb.WriteString(strconv.Itoa(ordinal))
+			start = end
+			i = end
+		default:
+			i++
+		}
+	}
+	if len(names) == 0 {
+		return &namedStatement{sql: sql}, nil
+	}
+	sb.WriteString(sql[start:])
+	return &namedStatement{sql: sb.String(), names: names}, nil
+}
+
+// startsNamedParam reports whether a named parameter starts at sql[i].
+func startsNamedParam(sql string, i int) bool {
+	c := sql[i]
+	if c != ':' && c != '@' || i+1 >= len(sql) || !isIdentStartByte(sql[i+1]) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	prev := sql[i-1]
+	return !isIdentByte(prev) && !(c == ':' && prev == '[') && !(c == '@' && isOperatorByte(prev))
+}
+
+// skipQuoted returns the index just past the quoted string or identifier
+// starting at sql[i], in which a doubled quote stands for a quote, and, if
+// escapes is set, a backslash escapes the byte after it.
+func skipQuoted(sql string, i int, quote byte, escapes bool) (int, bool) {
+	for j := i + 1; j < len(sql); j++ {
+		switch sql[j] {
+		case '\\':
+			if escapes {
+				j++
+			}
+		case quote:
+			if j+1 < len(sql) && sql[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1, true
+		}
+	}
+	return 0, false
+}
+
+// skipBlockComment returns the index just past the block comment starting
+// at sql[i]. Block comments nest in Postgres.
+func skipBlockComment(sql string, i int) (int, bool) {
+	depth := 0
+	for j := i; j+1 < len(sql); j++ {
+		switch {
+		case sql[j] == '/' && sql[j+1] == '*':
+			depth++
+			j++
+		case sql[j] == '*' && sql[j+1] == '/':
+			depth--
+			j++
+			if depth == 0 {
+				return j + 1, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// skipDollarQuoted returns the index just past the dollar-quoted string
+// starting at sql[i], such as $$text$$ or $fn$text$fn$, or false if sql[i]
+// doesn't start one.
+func skipDollarQuoted(sql string, i int) (int, bool, error) {
+	j := i + 1
+	if j < len(sql) && isIdentStartByte(sql[j]) {
+		for j < len(sql) && sql[j] != '$' && isIdentByte(sql[j]) {
+			j++
+		}
+	}
+	if j >= len(sql) || sql[j] != '$' {
+		return 0, false, nil
+	}
+	tag := sql[i : j+1]
+	end := strings.Index(sql[j+1:], tag)
+	if end < 0 {
+		return 0, false, fmt.Errorf("unterminated dollar-quoted string %s in query", tag)
+	}
+	return j + 1 + end + len(tag), true, nil
+}
+
+func isIdentStartByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func isDigitByte(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isIdentByte reports whether c can be part of an identifier. Bytes of
+// multibyte UTF-8 characters are, since Postgres allows letters other
+// than ASCII ones in identifiers.
+func isIdentByte(c byte) bool {
+	return isIdentStartByte(c) || isDigitByte(c) || c == '$' || c >= 0x80
+}
+
+// isOperatorByte reports whether c can be part of a Postgres operator.
+func isOperatorByte(c byte) bool {
+	return strings.IndexByte("+-*/<>=~!@#%^&|`?", c) >= 0
+}
+
+// namedValues returns the values of the parameters named names, taken from arg.
+func namedValues(names []string, arg any) ([]any, error) {
+	v := reflect.ValueOf(arg)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, errors.New("named parameters given a nil pointer")
+		}
+		v = v.Elem()
+	}
+
+	values := make([]any, len(names))
+	switch {
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		for i, name := range names {
+			value := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+			if !value.IsValid() {
+				return nil, fmt.Errorf("no value for named parameter %s", name)
+			}
+			values[i] = value.Interface()
+		}
+	case v.Kind() == reflect.Struct:
+		columns, err := structColumns(v.Type())
+		if err != nil {
+			return nil, err
+		}
+		for i, name := range names {
+			col, err := namedColumn(v.Type(), columns, name)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = fieldValue(v, col)
+		}
+	case len(names) == 0:
+		// Nothing to bind, so arg doesn't matter.
+	default:
+		return nil, fmt.Errorf("named parameters must be given a map with string keys or a struct, not %T", arg)
+	}
+	return values, nil
+}
+
+// namedColumn returns the column of structType that the named parameter
+// name takes its value from.
+func namedColumn(structType reflect.Type, columns []structColumn, name string) (structColumn, error) {
+	var found structColumn
+	ok := false
+	for _, col := range columns {
+		if !SimpleNameMapper.Match(name, col.name) {
+			continue
+		}
+		if ok {
+			return structColumn{}, fmt.Errorf("named parameter %s matches both column %s and column %s of struct %s", name, found.name, col.name, structType)
+		}
+		found = col
+		ok = true
+	}
+	if !ok {
+		return structColumn{}, fmt.Errorf("no value for named parameter %s in struct %s", name, structType)
+	}
+	return found, nil
+}
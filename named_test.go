@@ -0,0 +1,155 @@
+package pgxtras_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jackc/pgx/v5"
+	"github.com/manniwood/pgxtras"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBindNamed(t *testing.T) {
+	args := map[string]any{"id": 1, "name": "Joe", "x": 3}
+	tests := map[string]struct {
+		input    string
+		wantSQL  string
+		wantArgs []any
+	}{
+		"colon":             {input: "select * from t where id = :id", wantSQL: "select * from t where id = $1", wantArgs: []any{1}},
+		"at sign":           {input: "select * from t where id = @id", wantSQL: "select * from t where id = $1", wantArgs: []any{1}},
+		"repeated":          {input: "select :id, :name, :id", wantSQL: "select $1, $2, $1", wantArgs: []any{1, "Joe"}},
+		"no parameters":     {input: "select 1", wantSQL: "select 1", wantArgs: []any{}},
+		"cast":              {input: "select :id::int8, x::text", wantSQL: "select $1::int8, x::text", wantArgs: []any{1}},
+		"string literal":    {input: "select ':id', 'it''s :x', :name", wantSQL: "select ':id', 'it''s :x', $1", wantArgs: []any{"Joe"}},
+		"escape string":     {input: `select E'\':id', :name`, wantSQL: `select E'\':id', $1`, wantArgs: []any{"Joe"}},
+		"quoted identifier": {input: `select ":id" from t where id = :id`, wantSQL: `select ":id" from t where id = $1`, wantArgs: []any{1}},
+		"line comment":      {input: "select :id -- and :name\n, :x", wantSQL: "select $1 -- and :name\n, $2", wantArgs: []any{1, 3}},
+		"block comment":     {input: "select /* :name /* :x */ :name */ :id", wantSQL: "select /* :name /* :x */ :name */ $1", wantArgs: []any{1}},
+		"dollar quote":      {input: "select $$ :id $$, $fn$ $$ :x $fn$, :name", wantSQL: "select $$ :id $$, $fn$ $$ :x $fn$, $1", wantArgs: []any{"Joe"}},
+		"operators":         {input: "select a <@ b, c @@ d, e@>f, g<@x", wantSQL: "select a <@ b, c @@ d, e@>f, g<@x", wantArgs: []any{}},
+		"array slice":       {input: "select arr[lo:hi], arr[:hi], arr[1:]", wantSQL: "select arr[lo:hi], arr[:hi], arr[1:]", wantArgs: []any{}},
+		"slice parameters":  {input: "select arr[@id:@x], arr[:x], arr[@x]", wantSQL: "select arr[$1:$2], arr[:x], arr[$2]", wantArgs: []any{1, 3}},
+		"identifier dollar": {input: "select a$b, :id", wantSQL: "select a$b, $1", wantArgs: []any{1}},
+	}
+	for testName, testCase := range tests {
+		t.Run(testName, func(t *testing.T) {
+			gotSQL, gotArgs, err := pgxtras.BindNamed(testCase.input, args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			diff := cmp.Diff(testCase.wantSQL, gotSQL)
+			if diff != "" {
+				t.Fatalf(diff)
+			}
+			diff = cmp.Diff(testCase.wantArgs, gotArgs)
+			if diff != "" {
+				t.Fatalf(diff)
+			}
+		})
+	}
+}
+
+func TestBindNamedStruct(t *testing.T) {
+	type base struct {
+		TenantID int32
+	}
+	type params struct {
+		base
+		UserID   int64
+		Email    *string
+		Nickname string `db:"nick"`
+	}
+
+	sql, args, err := pgxtras.BindNamed(
+		"select * from users where tenant_id = :tenant_id and id = :userid and email = :email and nick = @nick",
+		&params{base: base{TenantID: 7}, UserID: 42, Nickname: "JJ"})
+	require.NoError(t, err)
+	assert.Equal(t, "select * from users where tenant_id = $1 and id = $2 and email = $3 and nick = $4", sql)
+	assert.Equal(t, []any{int32(7), int64(42), (*string)(nil), "JJ"}, args)
+}
+
+func TestBindNamedErrors(t *testing.T) {
+	type params struct {
+		UserID int64
+		UserId int64 `db:"userid"`
+	}
+
+	tests := map[string]struct {
+		input string
+		arg   any
+		want  string
+	}{
+		"missing map value":     {input: "select :id", arg: map[string]any{}, want: "no value for named parameter id"},
+		"missing field":         {input: "select :id", arg: params{}, want: "no value for named parameter id in struct pgxtras_test.params"},
+		"ambiguous field":       {input: "select :user_id", arg: params{}, want: "named parameter user_id matches both column user_id and column userid of struct pgxtras_test.params"},
+		"positional parameters": {input: "select :id, $1", arg: map[string]any{"id": 1}, want: "named parameters can't be mixed with positional parameters"},
+		"unterminated string":   {input: "select ':id", arg: map[string]any{}, want: "unterminated quoted string in query"},
+		"unterminated dollar":   {input: "select $x$ :id", arg: map[string]any{}, want: "unterminated dollar-quoted string $x$ in query"},
+		"not a map or struct":   {input: "select :id", arg: 42, want: "named parameters must be given a map with string keys or a struct, not int"},
+		"nil pointer":           {input: "select :id", arg: (*params)(nil), want: "named parameters given a nil pointer"},
+	}
+	for testName, testCase := range tests {
+		t.Run(testName, func(t *testing.T) {
+			_, _, err := pgxtras.BindNamed(testCase.input, testCase.arg)
+			assert.EqualError(t, err, testCase.want)
+		})
+	}
+}
+
+func TestBindNamedCache(t *testing.T) {
+	const sql = "select :id -- TestBindNamedCache"
+	first, err := pgxtras.ParseNamedCached(sql)
+	require.NoError(t, err)
+	second, err := pgxtras.ParseNamedCached(sql)
+	require.NoError(t, err)
+	assert.Same(t, first, second, "a repeated query is parsed again")
+
+	// queries that don't parse aren't cached
+	cached := pgxtras.NamedStatementCacheLen()
+	_, err = pgxtras.ParseNamedCached("select ':id")
+	assert.Error(t, err)
+	assert.Equal(t, cached, pgxtras.NamedStatementCacheLen())
+
+	// the cache is bounded, dropping the least recently used queries
+	for i := 0; i < pgxtras.NamedStatementCacheSize+10; i++ {
+		_, _, err := pgxtras.BindNamed(fmt.Sprintf("select :id + %d", i), map[string]any{"id": 1})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, pgxtras.NamedStatementCacheSize, pgxtras.NamedStatementCacheLen())
+	third, err := pgxtras.ParseNamedCached(sql)
+	require.NoError(t, err)
+	assert.NotSame(t, first, third)
+}
+
+func TestNamed(t *testing.T) {
+	type person struct {
+		ID   int32
+		Name string
+	}
+
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		_, err := conn.Exec(ctx, `create temporary table people (id int4 primary key, name text not null)`)
+		require.NoError(t, err)
+
+		_, err = conn.Exec(ctx, `insert into people (id, name) values (:id, :name)`, pgxtras.Named(person{ID: 1, Name: "Joe"}))
+		require.NoError(t, err)
+
+		batch := &pgx.Batch{}
+		batch.Queue(`insert into people (id, name) values (@id, @name)`, pgxtras.Named(pgx.NamedArgs{"id": 2, "name": "Ann"}))
+		batch.Queue(`insert into people (id, name) values (@id, @name)`, pgxtras.Named(&person{ID: 3, Name: "Bob"}))
+		require.NoError(t, conn.SendBatch(ctx, batch).Close())
+
+		rows, _ := conn.Query(ctx, `select id, name from people where name <> ':name' and id >= :min::int4 order by id`,
+			pgxtras.Named(map[string]any{"min": 2}))
+		people, err := pgx.CollectRows(rows, pgxtras.RowToStructBySimpleName[person])
+		require.NoError(t, err)
+		assert.Equal(t, []person{{ID: 2, Name: "Ann"}, {ID: 3, Name: "Bob"}}, people)
+
+		_, err = conn.Exec(ctx, `select :id`, pgxtras.Named(map[string]any{"id": 1}), 2)
+		assert.EqualError(t, err, "named parameters can't be mixed with positional arguments")
+	})
+}
@@ -0,0 +1,65 @@
+package pgxtras
+
+import "strings"
+
+// NameMapper decides which struct field a returned column is scanned into.
+// Implement it to teach RowToStructWith and RowToAddrOfStructWith a naming
+// convention of your own; for instance, to strip a legacy "tbl_" prefix
+// from column names before comparing them the way SimpleNameMapper does:
+//
+//	type tblPrefixMapper struct{}
+//
+//	func (tblPrefixMapper) Match(columnName, fieldName string) bool {
+//		return pgxtras.SimpleNameMapper.Match(strings.TrimPrefix(columnName, "tbl_"), fieldName)
+//	}
+type NameMapper interface {
+	// Match reports whether the column named columnName should be
+	// scanned into the struct field named fieldName. If the struct field
+	// has a "db" tag, fieldName is the name given in the tag instead.
+	Match(columnName, fieldName string) bool
+}
+
+// NameMapperFunc is an adapter to allow the use of ordinary functions as a NameMapper.
+type NameMapperFunc func(columnName, fieldName string) bool
+
+// Match calls f(columnName, fieldName).
+func (f NameMapperFunc) Match(columnName, fieldName string) bool {
+	return f(columnName, fieldName)
+}
+
+var (
+	// SnakeToCamelNameMapper matches a column to a struct field when
+	// SnakeToCamel of the column name is exactly the field name.
+	// It is the NameMapper used by RowToStructBySnakeToCamelName.
+	SnakeToCamelNameMapper NameMapper = snakeToCamelNameMapper{}
+
+	// SimpleNameMapper matches a column to a struct field when both have
+	// the same "simple" name. A "simple" name is lowercased with all
+	// underscores removed. It is the NameMapper used by RowToStructBySimpleName.
+	SimpleNameMapper NameMapper = simpleNameMapper{}
+)
+
+// NewSnakeToCamelNameMapper returns a NameMapper like SnakeToCamelNameMapper,
+// except that it translates column names using SnakeToCamelWithInitialisms with
+// the given initialisms. For instance, NewSnakeToCamelNameMapper(CommonInitialisms...)
+// matches the column user_id to the struct field UserID.
+func NewSnakeToCamelNameMapper(initialisms ...string) NameMapper {
+	return snakeToCamelNameMapper{initialisms: initialisms}
+}
+
+type snakeToCamelNameMapper struct {
+	initialisms []string
+}
+
+func (m snakeToCamelNameMapper) Match(columnName, fieldName string) bool {
+	return SnakeToCamelWithInitialisms(columnName, m.initialisms...) == fieldName
+}
+
+type simpleNameMapper struct{}
+
+func (simpleNameMapper) Match(columnName, fieldName string) bool {
+	return strings.EqualFold(
+		strings.ReplaceAll(columnName, "_", ""),
+		strings.ReplaceAll(fieldName, "_", ""),
+	)
+}
@@ -0,0 +1,313 @@
+package pgxtras
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrInvalidPageToken is returned by Paginate when given a page token
+// that it didn't make, or made for a different list of sort keys.
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// SortKey is one of the columns that Paginate sorts by. Column is the name
+// of a column returned by the query being paginated. NULLs sort as Postgres
+// sorts them by default: after everything else in ascending order, and
+// before everything else in descending order.
+type SortKey struct {
+	Column string
+	Desc   bool
+}
+
+// PageRequest says which page Paginate is to return.
+type PageRequest struct {
+	// Keys are the columns to sort by, most significant first. Together
+	// they must be unique to each row (the last one is often a primary
+	// key), or else rows with the same keys may be skipped between pages.
+	Keys []SortKey
+	// Size is the most rows a page holds.
+	Size int
+	// Token is the NextToken or PrevToken of a Page, or empty for the first page.
+	Token string
+}
+
+// Page is a page of results returned by Paginate.
+type Page[T any] struct {
+	Items []T
+	// NextToken and PrevToken are the tokens of the pages after and before
+	// this one, or empty if there is no such page.
+	NextToken string
+	PrevToken string
+}
+
+// pageToken is what the opaque page tokens hold: the direction to page in,
+// a hash of the sort keys it was made for, and the text format values of
+// the sort keys of the row to page from.
+type pageToken struct {
+	Backward bool      `json:"b,omitempty"`
+	Sort     uint32    `json:"s"`
+	Keys     []*string `json:"k"`
+}
+
+// sortKeysHash returns a hash of the columns and directions of keys, so that
+// a page token can be checked against the sort keys it is used with.
+func sortKeysHash(keys []SortKey) uint32 {
+	h := fnv.New32a()
+	for _, key := range keys {
+		h.Write([]byte(key.Column))
+		if key.Desc {
+			h.Write([]byte{0, 1})
+		} else {
+			h.Write([]byte{0, 0})
+		}
+	}
+	return h.Sum32()
+}
+
+func (t pageToken) encode() string {
+	b, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodePageToken(s string, keys []SortKey) (pageToken, error) {
+	var t pageToken
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return t, ErrInvalidPageToken
+	}
+	if err := json.Unmarshal(b, &t); err != nil || len(t.Keys) != len(keys) || t.Sort != sortKeysHash(keys) {
+		return t, ErrInvalidPageToken
+	}
+	return t, nil
+}
+
+// Paginate returns a page of the rows returned by sql (with args), scanning
+// each one using fn, using keyset pagination: rather than using OFFSET,
+// which gets slower the further in the page is, it picks up after the sort
+// keys of the last row of the page before, so that every page is as quick
+// to get as the first. For instance,
+//
+//	page, err := pgxtras.Paginate(ctx, conn, pgxtras.RowToStructBySimpleName[User],
+//		pgxtras.PageRequest{
+//			Keys:  []pgxtras.SortKey{{Column: "created_at", Desc: true}, {Column: "id"}},
+//			Size:  50,
+//			Token: r.URL.Query().Get("page"),
+//		},
+//		`select id, name, created_at from users where org_id = $1`, orgID)
+//
+// sql must not have an ORDER BY or LIMIT of its own, and its columns must
+// have distinct names, because it becomes a subquery of the query that
+// Paginate runs.
+func Paginate[T any](ctx context.Context, q Querier, fn pgx.RowToFunc[T], req PageRequest, sql string, args ...any) (*Page[T], error) {
+	if len(req.Keys) == 0 {
+		return nil, errors.New("no sort keys to paginate by")
+	}
+	if req.Size <= 0 {
+		return nil, fmt.Errorf("page size must be positive, but got %d", req.Size)
+	}
+
+	var token pageToken
+	if req.Token != "" {
+		var err error
+		token, err = decodePageToken(req.Token, req.Keys)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Paging backward is paging forward with every sort key reversed,
+	// and then reversing the page.
+	keys := req.Keys
+	if token.Backward {
+		keys = make([]SortKey, len(req.Keys))
+		for i, key := range req.Keys {
+			keys[i] = SortKey{Column: key.Column, Desc: !key.Desc}
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("select * from (")
+	sb.WriteString(sql)
+	sb.WriteString(") as pgxtras_page")
+	if req.Token != "" {
+		where, whereArgs := keysetPredicate(keys, token.Keys, len(args))
+		sb.WriteString(" where ")
+		sb.WriteString(where)
+		args = append(args[:len(args):len(args)], whereArgs...)
+	}
+	sb.WriteString(" order by ")
+	for i, key := range keys {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(pgx.Identifier{key.Column}.Sanitize())
+		if key.Desc {
+			sb.WriteString(" desc")
+		}
+	}
+	fmt.Fprintf(&sb, " limit %d", req.Size+1)
+
+	rows, err := q.Query(ctx, sb.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []T
+	var itemKeys [][]*string
+	var keyIndexes []int
+	for rows.Next() {
+		if keyIndexes == nil {
+			keyIndexes, err = sortKeyIndexes(keys, rows)
+			if err != nil {
+				return nil, err
+			}
+		}
+		value, err := fn(rows)
+		if err != nil {
+			return nil, err
+		}
+		rowKeys := make([]*string, len(keyIndexes))
+		for i, index := range keyIndexes {
+			text, err := textValue(rows, index)
+			if err != nil {
+				return nil, fmt.Errorf("can't format sort key %s: %w", keys[i].Column, err)
+			}
+			if text != nil {
+				str := string(text)
+				rowKeys[i] = &str
+			}
+		}
+		items = append(items, value)
+		itemKeys = append(itemKeys, rowKeys)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	more := len(items) > req.Size
+	if more {
+		items = items[:req.Size]
+		itemKeys = itemKeys[:req.Size]
+	}
+	if token.Backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+			itemKeys[i], itemKeys[j] = itemKeys[j], itemKeys[i]
+		}
+	}
+
+	page := &Page[T]{Items: items}
+	if len(items) == 0 {
+		return page, nil
+	}
+	sort := sortKeysHash(req.Keys)
+	first := itemKeys[0]
+	last := itemKeys[len(itemKeys)-1]
+	if token.Backward {
+		// We came here from the page after this one.
+		page.NextToken = pageToken{Sort: sort, Keys: last}.encode()
+		if more {
+			page.PrevToken = pageToken{Backward: true, Sort: sort, Keys: first}.encode()
+		}
+	} else {
+		if more {
+			page.NextToken = pageToken{Sort: sort, Keys: last}.encode()
+		}
+		if req.Token != "" {
+			page.PrevToken = pageToken{Backward: true, Sort: sort, Keys: first}.encode()
+		}
+	}
+	return page, nil
+}
+
+// sortKeyIndexes returns the indexes of the columns of rows named by keys.
+func sortKeyIndexes(keys []SortKey, rows pgx.Rows) ([]int, error) {
+	indexes := make([]int, len(keys))
+	for i, key := range keys {
+		indexes[i] = -1
+		for j, fd := range rows.FieldDescriptions() {
+			if fd.Name == key.Column {
+				indexes[i] = j
+				break
+			}
+		}
+		if indexes[i] < 0 {
+			return nil, fmt.Errorf("no returned column matches sort key %s", key.Column)
+		}
+	}
+	return indexes, nil
+}
+
+// keysetPredicate returns a WHERE condition for the rows that sort after the
+// row whose sort keys have the text format values values, along with the
+// arguments for it, which are numbered from numArgs+1. For sort keys a and
+// b, that is
+//
+//	a after $1 or (a = $1 and b after $2)
+//
+// where "after" takes the direction of the key, and NULLs, into account.
+// Postgres works out the types of the arguments from the columns they are
+// compared with, so the text format values can be passed as is.
+func keysetPredicate(keys []SortKey, values []*string, numArgs int) (string, []any) {
+	var args []any
+	var params []string
+	for _, value := range values {
+		if value == nil {
+			params = append(params, "")
+			continue
+		}
+		args = append(args, *value)
+		params = append(params, fmt.Sprintf("$%d", numArgs+len(args)))
+	}
+
+	var terms []string
+	for i := range keys {
+		var conds []string
+		for j := 0; j < i; j++ {
+			conds = append(conds, keyEqual(keys[j], params[j]))
+		}
+		after := keyAfter(keys[i], params[i])
+		if after == "" {
+			continue
+		}
+		conds = append(conds, after)
+		terms = append(terms, "("+strings.Join(conds, " and ")+")")
+	}
+	if len(terms) == 0 {
+		return "false", args
+	}
+	return "(" + strings.Join(terms, " or ") + ")", args
+}
+
+// keyEqual returns a condition for key equalling param, or being NULL if
+// param is empty.
+func keyEqual(key SortKey, param string) string {
+	col := pgx.Identifier{key.Column}.Sanitize()
+	if param == "" {
+		return col + " is null"
+	}
+	return col + " = " + param
+}
+
+// keyAfter returns a condition for key sorting after param, or after NULL
+// if param is empty, or the empty string if nothing sorts after it.
+func keyAfter(key SortKey, param string) string {
+	col := pgx.Identifier{key.Column}.Sanitize()
+	switch {
+	case param == "" && key.Desc:
+		return col + " is not null"
+	case param == "":
+		return ""
+	case key.Desc:
+		return col + " < " + param
+	default:
+		return "(" + col + " > " + param + " or " + col + " is null)"
+	}
+}
@@ -0,0 +1,84 @@
+package pgxtras_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/manniwood/pgxtras"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaginate(t *testing.T) {
+	type item struct {
+		ID   int32
+		Grp  *int32
+		Name string
+	}
+
+	const query = `
+select n as id, case when n % 4 = 0 then null else n % 3 end as grp, 'name' || n as name
+  from generate_series(1, $1::int4) n`
+
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		rows, _ := conn.Query(ctx, `select * from (`+query+`) q order by grp desc, id`, 20)
+		all, err := pgx.CollectRows(rows, pgxtras.RowToStructBySimpleName[item])
+		require.NoError(t, err)
+
+		req := pgxtras.PageRequest{
+			Keys: []pgxtras.SortKey{{Column: "grp", Desc: true}, {Column: "id"}},
+			Size: 6,
+		}
+
+		// forward through every page
+		var pages [][]item
+		for {
+			page, err := pgxtras.Paginate(ctx, conn, pgxtras.RowToStructBySimpleName[item], req, query, 20)
+			require.NoError(t, err)
+			pages = append(pages, page.Items)
+			if len(pages) == 1 {
+				assert.Empty(t, page.PrevToken)
+			} else {
+				assert.NotEmpty(t, page.PrevToken)
+			}
+			if page.NextToken == "" {
+				break
+			}
+			req.Token = page.NextToken
+		}
+		require.Len(t, pages, 4)
+		var got []item
+		for _, p := range pages {
+			got = append(got, p...)
+		}
+		assert.Equal(t, all, got)
+
+		// and back again
+		for i := len(pages) - 1; i > 0; i-- {
+			page, err := pgxtras.Paginate(ctx, conn, pgxtras.RowToStructBySimpleName[item], req, query, 20)
+			require.NoError(t, err)
+			assert.Equal(t, pages[i], page.Items)
+			req.Token = page.PrevToken
+		}
+		page, err := pgxtras.Paginate(ctx, conn, pgxtras.RowToStructBySimpleName[item], req, query, 20)
+		require.NoError(t, err)
+		assert.Equal(t, pages[0], page.Items)
+		assert.Empty(t, page.PrevToken)
+		assert.NotEmpty(t, page.NextToken)
+
+		// a token made for a different sort, even with as many keys, is refused
+		for _, keys := range [][]pgxtras.SortKey{
+			{{Column: "grp"}, {Column: "id"}},
+			{{Column: "name", Desc: true}, {Column: "id"}},
+		} {
+			other := pgxtras.PageRequest{Keys: keys, Size: 6, Token: page.NextToken}
+			_, err = pgxtras.Paginate(ctx, conn, pgxtras.RowToStructBySimpleName[item], other, query, 20)
+			assert.ErrorIs(t, err, pgxtras.ErrInvalidPageToken)
+		}
+
+		req.Token = "not a token"
+		_, err = pgxtras.Paginate(ctx, conn, pgxtras.RowToStructBySimpleName[item], req, query, 20)
+		assert.ErrorIs(t, err, pgxtras.ErrInvalidPageToken)
+	})
+}
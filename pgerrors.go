@@ -0,0 +1,107 @@
+package pgxtras
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SQLState returns the SQLSTATE code of err if err is, or wraps, a
+// *pgconn.PgError; otherwise it returns the empty string.
+func SQLState(err error) string {
+	if pgErr := asPgError(err); pgErr != nil {
+		return pgErr.Code
+	}
+	return ""
+}
+
+// IsUniqueViolation reports whether err is a unique_violation (23505).
+func IsUniqueViolation(err error) bool {
+	return SQLState(err) == "23505"
+}
+
+// IsForeignKeyViolation reports whether err is a foreign_key_violation (23503).
+func IsForeignKeyViolation(err error) bool {
+	return SQLState(err) == "23503"
+}
+
+// IsNotNullViolation reports whether err is a not_null_violation (23502).
+func IsNotNullViolation(err error) bool {
+	return SQLState(err) == "23502"
+}
+
+// IsCheckViolation reports whether err is a check_violation (23514).
+func IsCheckViolation(err error) bool {
+	return SQLState(err) == "23514"
+}
+
+// IsSerializationFailure reports whether err is a serialization_failure (40001).
+func IsSerializationFailure(err error) bool {
+	return SQLState(err) == "40001"
+}
+
+// IsDeadlock reports whether err is a deadlock_detected (40P01).
+func IsDeadlock(err error) bool {
+	return SQLState(err) == "40P01"
+}
+
+// IsQueryCanceled reports whether err is a query_canceled (57014), as
+// happens when a statement times out or is cancelled.
+func IsQueryCanceled(err error) bool {
+	return SQLState(err) == "57014"
+}
+
+// IsConnectionException reports whether err is one of the connection
+// exceptions (class 08).
+func IsConnectionException(err error) bool {
+	code := SQLState(err)
+	return len(code) == 5 && code[:2] == "08"
+}
+
+// IsRetryable reports whether the transaction that err came from can simply
+// be run again: because it failed with a serialization failure or a deadlock,
+// or because pgx says the failure happened before anything was sent to the
+// server (see pgconn.SafeToRetry).
+func IsRetryable(err error) bool {
+	if IsSerializationFailure(err) || IsDeadlock(err) {
+		return true
+	}
+	var safeErr interface{ SafeToRetry() bool }
+	return errors.As(err, &safeErr) && safeErr.SafeToRetry()
+}
+
+// ConstraintName returns the name of the constraint that err is about, or
+// the empty string if err isn't a *pgconn.PgError about a constraint. For a
+// unique violation, say, it is the name of the violated unique index.
+func ConstraintName(err error) string {
+	if pgErr := asPgError(err); pgErr != nil {
+		return pgErr.ConstraintName
+	}
+	return ""
+}
+
+// TableName returns the name of the table that err is about, or the empty
+// string if err isn't a *pgconn.PgError about a table.
+func TableName(err error) string {
+	if pgErr := asPgError(err); pgErr != nil {
+		return pgErr.TableName
+	}
+	return ""
+}
+
+// ColumnName returns the name of the column that err is about, or the empty
+// string if err isn't a *pgconn.PgError about a column.
+func ColumnName(err error) string {
+	if pgErr := asPgError(err); pgErr != nil {
+		return pgErr.ColumnName
+	}
+	return ""
+}
+
+func asPgError(err error) *pgconn.PgError {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr
+	}
+	return nil
+}
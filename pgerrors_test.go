@@ -0,0 +1,78 @@
+package pgxtras_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/manniwood/pgxtras"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLStatePredicates(t *testing.T) {
+	predicates := map[string]func(error) bool{
+		"IsUniqueViolation":      pgxtras.IsUniqueViolation,
+		"IsForeignKeyViolation":  pgxtras.IsForeignKeyViolation,
+		"IsNotNullViolation":     pgxtras.IsNotNullViolation,
+		"IsCheckViolation":       pgxtras.IsCheckViolation,
+		"IsSerializationFailure": pgxtras.IsSerializationFailure,
+		"IsDeadlock":             pgxtras.IsDeadlock,
+		"IsQueryCanceled":        pgxtras.IsQueryCanceled,
+		"IsConnectionException":  pgxtras.IsConnectionException,
+		"IsRetryable":            pgxtras.IsRetryable,
+	}
+	tests := map[string]struct {
+		code string
+		want []string
+	}{
+		"unique violation":      {code: "23505", want: []string{"IsUniqueViolation"}},
+		"foreign key violation": {code: "23503", want: []string{"IsForeignKeyViolation"}},
+		"not null violation":    {code: "23502", want: []string{"IsNotNullViolation"}},
+		"check violation":       {code: "23514", want: []string{"IsCheckViolation"}},
+		"serialization failure": {code: "40001", want: []string{"IsSerializationFailure", "IsRetryable"}},
+		"deadlock":              {code: "40P01", want: []string{"IsDeadlock", "IsRetryable"}},
+		"query canceled":        {code: "57014", want: []string{"IsQueryCanceled"}},
+		"connection failure":    {code: "08006", want: []string{"IsConnectionException"}},
+		"syntax error":          {code: "42601"},
+	}
+	for testName, testCase := range tests {
+		t.Run(testName, func(t *testing.T) {
+			err := fmt.Errorf("doing something: %w", &pgconn.PgError{Code: testCase.code})
+			assert.Equal(t, testCase.code, pgxtras.SQLState(err))
+			for name, predicate := range predicates {
+				want := false
+				for _, w := range testCase.want {
+					want = want || w == name
+				}
+				assert.Equal(t, want, predicate(err), name)
+			}
+		})
+	}
+
+	assert.Equal(t, "", pgxtras.SQLState(errors.New("not from postgres")))
+	assert.False(t, pgxtras.IsUniqueViolation(nil))
+	assert.False(t, pgxtras.IsRetryable(errors.New("not from postgres")))
+}
+
+func TestConstraintName(t *testing.T) {
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		_, err := conn.Exec(ctx, `create temporary table pgxtras_users (id int4 primary key, email text not null constraint pgxtras_users_email_key unique)`)
+		require.NoError(t, err)
+		_, err = conn.Exec(ctx, `insert into pgxtras_users values (1, 'joe@example.com')`)
+		require.NoError(t, err)
+
+		_, err = conn.Exec(ctx, `insert into pgxtras_users values (2, 'joe@example.com')`)
+		err = fmt.Errorf("creating user: %w", err)
+		assert.True(t, pgxtras.IsUniqueViolation(err))
+		assert.Equal(t, "pgxtras_users_email_key", pgxtras.ConstraintName(err))
+		assert.Equal(t, "pgxtras_users", pgxtras.TableName(err))
+
+		_, err = conn.Exec(ctx, `insert into pgxtras_users values (3, null)`)
+		assert.True(t, pgxtras.IsNotNullViolation(err))
+		assert.Equal(t, "email", pgxtras.ColumnName(err))
+	})
+}
@@ -7,7 +7,6 @@ import (
 	"unicode"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgconn"
 )
 
 // CollectOneRowOK is CollectOneRow with the "comma OK idiom": think 'val, ok := myMap["foo"]'
@@ -27,120 +26,227 @@ func CollectOneRowOK[T any](rows pgx.Rows, fn pgx.RowToFunc[T]) (T, bool, error)
 	return value, true, nil
 }
 
-// RowToStructBySnakeToCamelName returns a T scanned from row. T must be a struct. T must have the same number of named public
-// fields as row has fields. The row and T fields will by matched by name, converting snake case column names to camel case struct field names.
-func RowToStructBySnakeToCamelName[T any](row pgx.CollectableRow) (T, error) {
-	var value T
-	err := row.Scan(&namedCamelStructRowScanner{ptrToStruct: &value})
-	return value, err
+// CollectExactlyOneRow is CollectOneRow, except that rather than ignoring any rows after
+// the first, it reads them all and returns an error if there was more than one. If no rows
+// are found, it returns ErrNoRows, as CollectOneRow does; if more than one row is found, it
+// returns a *TooManyRowsError, which errors.Is treats as ErrTooManyRows.
+func CollectExactlyOneRow[T any](rows pgx.Rows, fn pgx.RowToFunc[T]) (T, error) {
+	value, ok, err := CollectAtMostOneRowOK(rows, fn)
+	if err != nil {
+		return value, err
+	}
+	if !ok {
+		return value, pgx.ErrNoRows
+	}
+	return value, nil
 }
 
-// RowToAddrOfStructBySnakeToCamelName returns the address of a T scanned from row. T must be a struct. T must have the same number of named public
-// fields as row has fields. The row and T fields will by matched by name, converting snake case column names to camel case struct field names.
-func RowToAddrOfStructBySnakeToCamelName[T any](row pgx.CollectableRow) (*T, error) {
+// CollectAtMostOneRowOK is CollectOneRowOK, except that rather than ignoring any rows after
+// the first, it reads them all and returns a *TooManyRowsError if there was more than one.
+func CollectAtMostOneRowOK[T any](rows pgx.Rows, fn pgx.RowToFunc[T]) (T, bool, error) {
+	defer rows.Close()
+
 	var value T
-	err := row.Scan(&namedCamelStructRowScanner{ptrToStruct: &value})
-	return &value, err
-}
+	if !rows.Next() {
+		return value, false, rows.Err()
+	}
+	value, err := fn(rows)
+	if err != nil {
+		return value, false, err
+	}
 
-type namedCamelStructRowScanner struct {
-	ptrToStruct any
+	count := 1
+	for rows.Next() {
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		var zero T
+		return zero, false, err
+	}
+	if count > 1 {
+		var zero T
+		return zero, false, &TooManyRowsError{Count: count}
+	}
+	return value, true, nil
 }
 
-func (rs *namedCamelStructRowScanner) ScanRow(rows pgx.Rows) error {
-	dst := rs.ptrToStruct
-	dstValue := reflect.ValueOf(dst)
-	if dstValue.Kind() != reflect.Ptr {
-		return fmt.Errorf("dst not a pointer")
+// CollectRowsToMap iterates through rows, calling fn for each row, and collecting the results
+// into a map keyed by what keyFn returns for each result. If two rows have the same key, it
+// returns a *DuplicateKeyError, which errors.Is treats as ErrDuplicateKey. For instance,
+//
+//	usersByID, err := pgxtras.CollectRowsToMap(rows, pgxtras.RowToAddrOfStructBySimpleName[User],
+//		func(u *User) int64 { return u.ID })
+func CollectRowsToMap[K comparable, V any](rows pgx.Rows, fn pgx.RowToFunc[V], keyFn func(V) K) (map[K]V, error) {
+	defer rows.Close()
+
+	m := make(map[K]V)
+	for rows.Next() {
+		value, err := fn(rows)
+		if err != nil {
+			return nil, err
+		}
+		key := keyFn(value)
+		if _, ok := m[key]; ok {
+			return nil, &DuplicateKeyError{Key: key}
+		}
+		m[key] = value
 	}
 
-	dstElemValue := dstValue.Elem()
-	scanTargets, err := rs.appendScanTargets(dstElemValue, nil, rows.FieldDescriptions())
-
-	if err != nil {
-		return err
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
+	return m, nil
+}
 
-	for i, t := range scanTargets {
-		if t == nil {
-			return fmt.Errorf("struct doesn't have corresponding field to match returned column %s", rows.FieldDescriptions()[i].Name)
+// CollectRowsGrouped iterates through rows, calling fn for each row, and collecting the results
+// into a map of slices keyed by what keyFn returns for each result. Each slice holds the results
+// with the same key, in the order their rows were returned. For instance,
+//
+//	ordersByUserID, err := pgxtras.CollectRowsGrouped(rows, pgxtras.RowToAddrOfStructBySimpleName[Order],
+//		func(o *Order) int64 { return o.UserID })
+func CollectRowsGrouped[K comparable, V any](rows pgx.Rows, fn pgx.RowToFunc[V], keyFn func(V) K) (map[K][]V, error) {
+	defer rows.Close()
+
+	m := make(map[K][]V)
+	for rows.Next() {
+		value, err := fn(rows)
+		if err != nil {
+			return nil, err
 		}
+		key := keyFn(value)
+		m[key] = append(m[key], value)
 	}
 
-	return rows.Scan(scanTargets...)
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
 }
 
-const structTagKey = "db"
+// CollectRowsInBatches iterates through rows, calling fn for each row, and handing the results
+// to batchFn batchSize at a time, with any remainder in a final, shorter batch. So that memory
+// use stays bounded, the same backing array is used for every batch: batchFn must not keep the
+// slice it is given (or any part of it) after it returns.
+//
+// If fn or batchFn returns an error, CollectRowsInBatches closes rows and returns the error.
+// If rows.Err() returns an error, the final batch is not handed to batchFn.
+func CollectRowsInBatches[T any](rows pgx.Rows, fn pgx.RowToFunc[T], batchSize int, batchFn func([]T) error) error {
+	defer rows.Close()
+
+	if batchSize <= 0 {
+		return fmt.Errorf("batch size must be positive, but got %d", batchSize)
+	}
 
-func fieldPosByCamelName(fldDescs []pgconn.FieldDescription, field string) (i int) {
-	i = -1
-	for i, desc := range fldDescs {
-		if SnakeToCamel(desc.Name) == field {
-			return i
+	batch := make([]T, 0, batchSize)
+	for rows.Next() {
+		value, err := fn(rows)
+		if err != nil {
+			return err
+		}
+		batch = append(batch, value)
+		if len(batch) == batchSize {
+			if err := batchFn(batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
 		}
 	}
-	return
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		return batchFn(batch)
+	}
+	return nil
 }
 
-// SnakeToCamel takes a_typical_db_col in snake case and translates it to
-// TheCamelCase found in public fields of Go structs.
-func SnakeToCamel(s string) string {
-	snakeParts := strings.Split(s, "_")
-	var sb strings.Builder
-	for _, snakePart := range snakeParts {
-		theRunes := []rune(snakePart)
-		for i, rn := range theRunes {
-			if i == 0 {
-				rn = unicode.ToUpper(rn)
-			}
-			sb.WriteRune(rn)
-		}
+// RowToStructWith returns a RowToFunc that scans a row into a T. T must be a struct. T must have
+// the same number of named public fields as row has columns. The row columns and T fields will
+// be matched using mapper. The database column name can be overridden with a "db" struct tag,
+// in which case the tag, rather than the field name, is handed to mapper. If the "db" struct tag
+// is "-" then the field will be ignored.
+//
+// opts can relax or otherwise adjust how columns and fields are matched; see
+// LaxMatching and ReportAllMismatches.
+//
+// The column-to-field mapping for each struct type and column list is worked out once
+// and then reused, so call RowToStructWith once (say, at package level) and reuse
+// the returned function rather than calling RowToStructWith for every query.
+func RowToStructWith[T any](mapper NameMapper, opts ...StructScanOption) pgx.RowToFunc[T] {
+	plans := newStructScanPlanCache(mapper, opts...)
+	return func(row pgx.CollectableRow) (T, error) {
+		var value T
+		err := row.Scan(&structRowScanner{ptrToStruct: &value, plans: plans})
+		return value, err
 	}
-	return sb.String()
 }
 
-func (rs *namedCamelStructRowScanner) appendScanTargets(dstElemValue reflect.Value, scanTargets []any, fldDescs []pgconn.FieldDescription) ([]any, error) {
-	var err error
-	dstElemType := dstElemValue.Type()
+// RowToAddrOfStructWith returns a RowToFunc that scans a row into the address of a T.
+// It is otherwise the same as RowToStructWith.
+func RowToAddrOfStructWith[T any](mapper NameMapper, opts ...StructScanOption) pgx.RowToFunc[*T] {
+	plans := newStructScanPlanCache(mapper, opts...)
+	return func(row pgx.CollectableRow) (*T, error) {
+		var value T
+		err := row.Scan(&structRowScanner{ptrToStruct: &value, plans: plans})
+		return &value, err
+	}
+}
 
-	if scanTargets == nil {
-		scanTargets = make([]any, len(fldDescs))
+// StructScanOption adjusts how RowToStructWith and RowToAddrOfStructWith
+// match returned columns to struct fields.
+type StructScanOption func(*structScanPlanCache)
+
+// LaxMatching allows a struct field to have no matching column, in which case
+// the field is left at its zero value, and allows a column to have no matching
+// struct field, in which case the column is discarded. This lets one struct
+// be used with many queries that each select a different subset of its fields.
+func LaxMatching() StructScanOption {
+	return func(c *structScanPlanCache) {
+		c.lax = true
 	}
+}
 
-	for i := 0; i < dstElemType.NumField(); i++ {
-		sf := dstElemType.Field(i)
-		if sf.PkgPath != "" && !sf.Anonymous {
-			// Field is unexported, skip it.
-			continue
-		}
-		// Handle anoymous struct embedding, but do not try to handle embedded pointers.
-		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
-			scanTargets, err = rs.appendScanTargets(dstElemValue.Field(i), scanTargets, fldDescs)
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			dbTag, dbTagPresent := sf.Tag.Lookup(structTagKey)
-			if dbTagPresent {
-				dbTag = strings.Split(dbTag, ",")[0]
-			}
-			if dbTag == "-" {
-				// Field is ignored, skip it.
-				continue
-			}
-			colName := dbTag
-			if !dbTagPresent {
-				colName = sf.Name
-			}
-			fpos := fieldPosByCamelName(fldDescs, colName)
-			if fpos == -1 || fpos >= len(scanTargets) {
-				return nil, fmt.Errorf("no column in returned row matches struct field %s", colName)
-			}
-			scanTargets[fpos] = dstElemValue.Field(i).Addr().Interface()
-		}
+// ReportAllMismatches makes a failure to match columns and struct fields report
+// every unmatched column and struct field at once, instead of just the first
+// one found. It has no effect along with LaxMatching.
+func ReportAllMismatches() StructScanOption {
+	return func(c *structScanPlanCache) {
+		c.reportAll = true
 	}
+}
 
-	return scanTargets, err
+// PreferExactNames resolves what would otherwise be an ambiguous match in
+// favour of the column whose name is exactly (apart from case) that of the
+// struct field, or of its "db" tag. For instance, given a struct field UserID
+// and the columns user_id and userid, SimpleNameMapper matches both columns,
+// but PreferExactNames picks userid. The column passed over (user_id here) is
+// left unscanned, and isn't an UnmappedColumn error, even without LaxMatching.
+// Without PreferExactNames, a struct field that matches more than one column,
+// or a column that matches more than one struct field, is an AmbiguousColumn
+// error.
+func PreferExactNames() StructScanOption {
+	return func(c *structScanPlanCache) {
+		c.preferExact = true
+	}
+}
+
+// RowToStructBySnakeToCamelName returns a T scanned from row. T must be a struct. T must have the same number of named public
+// fields as row has fields. The row and T fields will by matched by name, converting snake case column names to camel case struct field names.
+// It is RowToStructWith using SnakeToCamelNameMapper.
+func RowToStructBySnakeToCamelName[T any](row pgx.CollectableRow) (T, error) {
+	var value T
+	err := row.Scan(&structRowScanner{ptrToStruct: &value, plans: snakeToCamelScanPlans})
+	return value, err
+}
+
+// RowToAddrOfStructBySnakeToCamelName returns the address of a T scanned from row. T must be a struct. T must have the same number of named public
+// fields as row has fields. The row and T fields will by matched by name, converting snake case column names to camel case struct field names.
+// It is RowToAddrOfStructWith using SnakeToCamelNameMapper.
+func RowToAddrOfStructBySnakeToCamelName[T any](row pgx.CollectableRow) (*T, error) {
+	var value T
+	err := row.Scan(&structRowScanner{ptrToStruct: &value, plans: snakeToCamelScanPlans})
+	return &value, err
 }
 
 // RowToStructBySimpleName returns a T scanned from row. T must be a struct. T must have
@@ -149,106 +255,149 @@ func (rs *namedCamelStructRowScanner) appendScanTargets(dstElemValue reflect.Val
 // The matching will be done on the "simple" names of each row column
 // and each struct field. A "simple" name is lowercased with
 // all underscores removed.
+// It is RowToStructWith using SimpleNameMapper.
 func RowToStructBySimpleName[T any](row pgx.CollectableRow) (T, error) {
 	var value T
-	err := row.Scan(&simpleNameStructRowScanner{ptrToStruct: &value})
+	err := row.Scan(&structRowScanner{ptrToStruct: &value, plans: simpleNameScanPlans})
+	return value, err
+}
+
+// RowToStructBySimpleNameLax returns a T scanned from row. T must be a struct.
+// It is RowToStructBySimpleName, except that T may have fields that do not match
+// any column, which are left at their zero value, and row may have columns that do
+// not match any field of T, which are discarded.
+// It is RowToStructWith using SimpleNameMapper and LaxMatching.
+func RowToStructBySimpleNameLax[T any](row pgx.CollectableRow) (T, error) {
+	var value T
+	err := row.Scan(&structRowScanner{ptrToStruct: &value, plans: simpleNameLaxScanPlans})
 	return value, err
 }
 
-// RowToStructBySimpleName returns the address of a T scanned from row. T must be a struct. T must have
+// RowToAddrOfStructBySimpleName returns the address of a T scanned from row. T must be a struct. T must have
 // the same number of named public
 // fields as row has columns. The row columns and T fields will by matched by name.
 // The matching will be done on the "simple" names of each row column
 // and each struct field. A "simple" name is lowercased with
 // all underscores removed.
+// It is RowToAddrOfStructWith using SimpleNameMapper.
 func RowToAddrOfStructBySimpleName[T any](row pgx.CollectableRow) (*T, error) {
 	var value T
-	err := row.Scan(&simpleNameStructRowScanner{ptrToStruct: &value})
+	err := row.Scan(&structRowScanner{ptrToStruct: &value, plans: simpleNameScanPlans})
+	return &value, err
+}
+
+// RowToAddrOfStructBySimpleNameLax returns the address of a T scanned from row.
+// It is otherwise the same as RowToStructBySimpleNameLax.
+func RowToAddrOfStructBySimpleNameLax[T any](row pgx.CollectableRow) (*T, error) {
+	var value T
+	err := row.Scan(&structRowScanner{ptrToStruct: &value, plans: simpleNameLaxScanPlans})
 	return &value, err
 }
 
-type simpleNameStructRowScanner struct {
+var (
+	snakeToCamelScanPlans  = newStructScanPlanCache(SnakeToCamelNameMapper)
+	simpleNameScanPlans    = newStructScanPlanCache(SimpleNameMapper)
+	simpleNameLaxScanPlans = newStructScanPlanCache(SimpleNameMapper, LaxMatching())
+)
+
+type structRowScanner struct {
 	ptrToStruct any
+	plans       *structScanPlanCache
 }
 
-func (rs *simpleNameStructRowScanner) ScanRow(rows pgx.Rows) error {
+func (rs *structRowScanner) ScanRow(rows pgx.Rows) error {
 	dst := rs.ptrToStruct
 	dstValue := reflect.ValueOf(dst)
 	if dstValue.Kind() != reflect.Ptr {
-		return fmt.Errorf("dst not a pointer")
+		return &MappingError{Kind: NotAStruct, ColumnIndex: -1}
 	}
 
 	dstElemValue := dstValue.Elem()
-	scanTargets, err := rs.appendScanTargets(dstElemValue, nil, rows.FieldDescriptions())
-
+	plan, err := rs.plans.plan(dstElemValue.Type(), rows.FieldDescriptions())
 	if err != nil {
 		return err
 	}
 
-	for i, t := range scanTargets {
-		if t == nil {
-			return fmt.Errorf("struct doesn't have corresponding field to match returned column %s", rows.FieldDescriptions()[i].Name)
-		}
+	err = rows.Scan(plan.scanTargets(dstElemValue)...)
+	if err != nil {
+		return plan.wrapScanError(err, rows.FieldDescriptions())
 	}
+	return nil
+}
 
-	return rows.Scan(scanTargets...)
+const structTagKey = "db"
+
+// CommonInitialisms are initialisms that Go style writes in all capitals in
+// identifiers, such as the ID in UserID. Pass them to SnakeToCamelWithInitialisms
+// or NewSnakeToCamelNameMapper to have user_id translated to UserID rather than UserId.
+var CommonInitialisms = []string{
+	"ACL", "API", "ASCII", "CPU", "CSS", "DNS", "EOF", "GUID", "HTML", "HTTP",
+	"HTTPS", "ID", "IP", "JSON", "LHS", "QPS", "RAM", "RHS", "RPC", "SLA",
+	"SMTP", "SQL", "SSH", "TCP", "TLS", "TTL", "UDP", "UI", "UID", "UUID",
+	"URI", "URL", "UTF8", "VM", "XML", "XMPP", "XSRF", "XSS",
 }
 
-func fieldPosBySimpleName(fldDescs []pgconn.FieldDescription, field string) (i int) {
-	i = -1
-	for i, desc := range fldDescs {
-		if strings.EqualFold(
-			strings.ReplaceAll(desc.Name, "_", ""),
-			strings.ReplaceAll(field, "_", ""),
-		) {
-			return i
+// SnakeToCamel takes a_typical_db_col in snake case and translates it to
+// TheCamelCase found in public fields of Go structs.
+func SnakeToCamel(s string) string {
+	return SnakeToCamelWithInitialisms(s)
+}
+
+// SnakeToCamelWithInitialisms is SnakeToCamel, except that any part of s
+// between underscores that is one of initialisms (compared case-insensitively)
+// is written just as it is given in initialisms, so
+// SnakeToCamelWithInitialisms("user_id", CommonInitialisms...) returns UserID.
+func SnakeToCamelWithInitialisms(s string, initialisms ...string) string {
+	snakeParts := strings.Split(s, "_")
+	var sb strings.Builder
+	for _, snakePart := range snakeParts {
+		if initialism, ok := lookupInitialism(snakePart, initialisms); ok {
+			sb.WriteString(initialism)
+			continue
+		}
+		theRunes := []rune(snakePart)
+		for i, rn := range theRunes {
+			if i == 0 {
+				rn = unicode.ToUpper(rn)
+			}
+			sb.WriteRune(rn)
 		}
 	}
-	return
+	return sb.String()
 }
 
-func (rs *simpleNameStructRowScanner) appendScanTargets(dstElemValue reflect.Value, scanTargets []any, fldDescs []pgconn.FieldDescription) ([]any, error) {
-	var err error
-	dstElemType := dstElemValue.Type()
-
-	if scanTargets == nil {
-		scanTargets = make([]any, len(fldDescs))
+func lookupInitialism(s string, initialisms []string) (string, bool) {
+	if s == "" {
+		return "", false
 	}
-
-	for i := 0; i < dstElemType.NumField(); i++ {
-		sf := dstElemType.Field(i)
-		if sf.PkgPath != "" && !sf.Anonymous {
-			// Field is unexported, skip it.
-			continue
+	for _, initialism := range initialisms {
+		if strings.EqualFold(s, initialism) {
+			return initialism, true
 		}
-		// Handle anoymous struct embedding, but do not try to handle embedded pointers.
-		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
-			scanTargets, err = rs.appendScanTargets(dstElemValue.Field(i), scanTargets, fldDescs)
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			dbTag, dbTagPresent := sf.Tag.Lookup(structTagKey)
-			if dbTagPresent {
-				dbTag = strings.Split(dbTag, ",")[0]
-			}
-			if dbTag == "-" {
-				// Field is ignored, skip it.
-				continue
-			}
-			colName := dbTag
-			if !dbTagPresent {
-				colName = sf.Name
-			}
-			fpos := fieldPosBySimpleName(fldDescs, colName)
-			if fpos == -1 || fpos >= len(scanTargets) {
-				return nil, fmt.Errorf("no column in returned row matches struct field %s", colName)
+	}
+	return "", false
+}
+
+// CamelToSnake takes TheCamelCase found in public fields of Go structs and
+// translates it to a_typical_db_col in snake case. A run of capitals is kept
+// together as one initialism, so HTTPAddress becomes http_address and UserID
+// becomes user_id, and digits stay with what they follow, so Address2Line
+// becomes address2_line.
+func CamelToSnake(s string) string {
+	runes := []rune(s)
+	var sb strings.Builder
+	sb.Grow(len(s) + 4)
+	for i, rn := range runes {
+		if unicode.IsUpper(rn) && i > 0 {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+				sb.WriteByte('_')
 			}
-			scanTargets[fpos] = dstElemValue.Field(i).Addr().Interface()
 		}
+		sb.WriteRune(unicode.ToLower(rn))
 	}
-
-	return scanTargets, err
+	return sb.String()
 }
 
 // RowToMapStrStr returns a map scanned from row.
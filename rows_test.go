@@ -2,11 +2,15 @@ package pgxtras_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/manniwood/pgxtras"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -40,6 +44,124 @@ func TestCollectOneRowOKNotFound(t *testing.T) {
 	})
 }
 
+func TestCollectExactlyOneRow(t *testing.T) {
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		rows, _ := conn.Query(ctx, `select 42`)
+		n, err := pgxtras.CollectExactlyOneRow(rows, pgx.RowTo[int32])
+		assert.NoError(t, err)
+		assert.Equal(t, int32(42), n)
+
+		rows, _ = conn.Query(ctx, `select 42 where false`)
+		_, err = pgxtras.CollectExactlyOneRow(rows, pgx.RowTo[int32])
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+
+		rows, _ = conn.Query(ctx, `select n from generate_series(1, 3) n`)
+		n, err = pgxtras.CollectExactlyOneRow(rows, pgx.RowTo[int32])
+		assert.ErrorIs(t, err, pgxtras.ErrTooManyRows)
+		var tooManyErr *pgxtras.TooManyRowsError
+		require.True(t, errors.As(err, &tooManyErr))
+		assert.Equal(t, 3, tooManyErr.Count)
+		assert.Equal(t, int32(0), n)
+	})
+}
+
+func TestCollectAtMostOneRowOK(t *testing.T) {
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		rows, _ := conn.Query(ctx, `select 42`)
+		n, ok, err := pgxtras.CollectAtMostOneRowOK(rows, pgx.RowTo[int32])
+		assert.NoError(t, err)
+		assert.Equal(t, int32(42), n)
+		assert.True(t, ok)
+
+		rows, _ = conn.Query(ctx, `select 42 where false`)
+		_, ok, err = pgxtras.CollectAtMostOneRowOK(rows, pgx.RowTo[int32])
+		assert.NoError(t, err)
+		assert.False(t, ok)
+
+		rows, _ = conn.Query(ctx, `select 42 union all select 43`)
+		_, ok, err = pgxtras.CollectAtMostOneRowOK(rows, pgx.RowTo[int32])
+		assert.ErrorIs(t, err, pgxtras.ErrTooManyRows)
+		assert.EqualError(t, err, "expected at most one row, but got 2")
+		assert.False(t, ok)
+	})
+}
+
+func TestCollectRowsToMap(t *testing.T) {
+	type person struct {
+		ID   int32
+		Name string
+	}
+
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		rows, _ := conn.Query(ctx, `select n as id, 'Joe' || n as name from generate_series(1, 3) n`)
+		m, err := pgxtras.CollectRowsToMap(rows, pgxtras.RowToAddrOfStructBySimpleName[person],
+			func(p *person) int32 { return p.ID })
+		require.NoError(t, err)
+		assert.Len(t, m, 3)
+		assert.Equal(t, "Joe2", m[2].Name)
+
+		rows, _ = conn.Query(ctx, `select n % 2 as id, 'Joe' || n as name from generate_series(1, 3) n`)
+		_, err = pgxtras.CollectRowsToMap(rows, pgxtras.RowToAddrOfStructBySimpleName[person],
+			func(p *person) int32 { return p.ID })
+		assert.ErrorIs(t, err, pgxtras.ErrDuplicateKey)
+		assert.EqualError(t, err, "more than one row has key 1")
+		var dupErr *pgxtras.DuplicateKeyError
+		require.True(t, errors.As(err, &dupErr))
+		assert.Equal(t, int32(1), dupErr.Key)
+	})
+}
+
+func TestCollectRowsGrouped(t *testing.T) {
+	type order struct {
+		ID     int32
+		UserID int32
+	}
+
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		rows, _ := conn.Query(ctx, `select n as id, n % 2 as user_id from generate_series(1, 5) n`)
+		m, err := pgxtras.CollectRowsGrouped(rows, pgxtras.RowToStructBySimpleName[order],
+			func(o order) int32 { return o.UserID })
+		require.NoError(t, err)
+		assert.Equal(t, map[int32][]order{
+			0: {{ID: 2, UserID: 0}, {ID: 4, UserID: 0}},
+			1: {{ID: 1, UserID: 1}, {ID: 3, UserID: 1}, {ID: 5, UserID: 1}},
+		}, m)
+	})
+}
+
+func TestCollectRowsInBatches(t *testing.T) {
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		rows, _ := conn.Query(ctx, `select n from generate_series(1, 10) n`)
+		var batches [][]int32
+		err := pgxtras.CollectRowsInBatches(rows, pgx.RowTo[int32], 4, func(batch []int32) error {
+			batches = append(batches, append([]int32(nil), batch...))
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, [][]int32{{1, 2, 3, 4}, {5, 6, 7, 8}, {9, 10}}, batches)
+
+		// an error from the callback stops the collection and closes rows
+		rows, _ = conn.Query(ctx, `select n from generate_series(1, 10) n`)
+		calls := 0
+		err = pgxtras.CollectRowsInBatches(rows, pgx.RowTo[int32], 4, func(batch []int32) error {
+			calls++
+			return errors.New("sink is full")
+		})
+		assert.EqualError(t, err, "sink is full")
+		assert.Equal(t, 1, calls)
+		var n int32
+		require.NoError(t, conn.QueryRow(ctx, `select 1`).Scan(&n))
+
+		rows, _ = conn.Query(ctx, `select 1`)
+		err = pgxtras.CollectRowsInBatches(rows, pgx.RowTo[int32], 0, func(batch []int32) error { return nil })
+		assert.EqualError(t, err, "batch size must be positive, but got 0")
+	})
+}
+
+// SnakeToCamel keeps the signature it has always had, so that it can still
+// be passed around as a func(string) string.
+var _ func(string) string = pgxtras.SnakeToCamel
+
 func TestSnakeToCamel(t *testing.T) {
 	tests := map[string]struct {
 		input string
@@ -62,6 +184,68 @@ func TestSnakeToCamel(t *testing.T) {
 	}
 }
 
+func TestSnakeToCamelWithInitialisms(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		want  string
+	}{
+		"trailing initialism":  {input: "user_id", want: "UserID"},
+		"leading initialism":   {input: "http_address", want: "HTTPAddress"},
+		"only initialism":      {input: "id", want: "ID"},
+		"upper case column":    {input: "USER_ID", want: "USERID"},
+		"initialism in middle": {input: "api_url_path", want: "APIURLPath"},
+		"not an initialism":    {input: "identity", want: "Identity"},
+		"digits":               {input: "utf8_name_2", want: "UTF8Name2"},
+		"unicode":              {input: "über_straße_id", want: "ÜberStraßeID"},
+		"empty":                {input: "", want: ""},
+	}
+	for testName, testCase := range tests {
+		t.Run(testName, func(t *testing.T) {
+			got := pgxtras.SnakeToCamelWithInitialisms(testCase.input, pgxtras.CommonInitialisms...)
+			diff := cmp.Diff(testCase.want, got)
+			if diff != "" {
+				t.Fatalf(diff)
+			}
+		})
+	}
+}
+
+func TestCamelToSnake(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		want  string
+	}{
+		"one segment":         {input: "Col", want: "col"},
+		"typical":             {input: "AColName", want: "a_col_name"},
+		"empty":               {input: "", want: ""},
+		"trailing initialism": {input: "UserID", want: "user_id"},
+		"leading initialism":  {input: "HTTPAddress", want: "http_address"},
+		"only initialism":     {input: "ID", want: "id"},
+		"two initialisms":     {input: "JSONAPI", want: "jsonapi"},
+		"lower camel case":    {input: "firstName", want: "first_name"},
+		"digits":              {input: "Address2Line", want: "address2_line"},
+		"trailing digits":     {input: "UTF8", want: "utf8"},
+		"underscores":         {input: "User_ID", want: "user_id"},
+		"unicode":             {input: "ÜberStraßeÉcole", want: "über_straße_école"},
+	}
+	for testName, testCase := range tests {
+		t.Run(testName, func(t *testing.T) {
+			got := pgxtras.CamelToSnake(testCase.input)
+			diff := cmp.Diff(testCase.want, got)
+			if diff != "" {
+				t.Fatalf(diff)
+			}
+		})
+	}
+}
+
+func TestNewSnakeToCamelNameMapper(t *testing.T) {
+	mapper := pgxtras.NewSnakeToCamelNameMapper(pgxtras.CommonInitialisms...)
+	assert.True(t, mapper.Match("user_id", "UserID"))
+	assert.False(t, mapper.Match("user_id", "UserId"))
+	assert.True(t, pgxtras.SnakeToCamelNameMapper.Match("user_id", "UserId"))
+}
+
 func TestRowToStructBySnakeToCamelName(t *testing.T) {
 	type person struct {
 		LastName      string
@@ -295,3 +479,394 @@ func TestRowToMapStrStr(t *testing.T) {
 		}
 	})
 }
+
+func TestRowToStructBySimpleNameColumnOrder(t *testing.T) {
+	type person struct {
+		ID        int32
+		FirstName string
+		LastName  string
+	}
+
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		// The same struct type scanned from differently-ordered columns
+		// must not reuse a column-to-field mapping meant for another query.
+		rows, _ := conn.Query(ctx, `select 1 as id, 'John' as first_name, 'Smith' as last_name`)
+		p, err := pgx.CollectOneRow(rows, pgxtras.RowToStructBySimpleName[person])
+		require.NoError(t, err)
+		assert.Equal(t, person{ID: 1, FirstName: "John", LastName: "Smith"}, p)
+
+		rows, _ = conn.Query(ctx, `select 'Smith' as last_name, 2 as id, 'Jane' as first_name`)
+		p, err = pgx.CollectOneRow(rows, pgxtras.RowToStructBySimpleName[person])
+		require.NoError(t, err)
+		assert.Equal(t, person{ID: 2, FirstName: "Jane", LastName: "Smith"}, p)
+	})
+}
+
+type benchmarkPerson struct {
+	ID            int32
+	FirstName     string
+	LastName      string
+	Email         string
+	HTTPAddress   string
+	City          string
+	LikesStarTrek bool
+	Age           int32
+}
+
+func newBenchmarkPersonRows() *staticRows {
+	return newStaticRows(
+		[]string{"id", "first_name", "last_name", "email", "http_address", "city", "likes_star_trek", "age"},
+		[]uint32{pgtype.Int4OID, pgtype.TextOID, pgtype.TextOID, pgtype.TextOID, pgtype.TextOID, pgtype.TextOID, pgtype.BoolOID, pgtype.Int4OID},
+		[]string{"1", "John", "Smith", "john.smith@example.com", "http://example.com/", "Ottawa", "t", "42"},
+	)
+}
+
+// BenchmarkRowToStructBySimpleName measures the cost of scanning one row,
+// without a database round trip to hide it, both with the scan plan cached,
+// as RowToStructBySimpleName does, and with it built afresh for every row,
+// as was done before scan plans were cached.
+func BenchmarkRowToStructBySimpleName(b *testing.B) {
+	b.Run("cached plan", func(b *testing.B) {
+		rows := newBenchmarkPersonRows()
+		rows.Next()
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := pgxtras.RowToStructBySimpleName[benchmarkPerson](rows); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("plan per row", func(b *testing.B) {
+		rows := newBenchmarkPersonRows()
+		rows.Next()
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			fn := pgxtras.RowToStructWith[benchmarkPerson](pgxtras.SimpleNameMapper)
+			if _, err := fn(rows); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkRowToStructBySnakeToCamelName(b *testing.B) {
+	type person struct {
+		Id            int32
+		FirstName     string
+		LastName      string
+		Email         string
+		HttpAddress   string
+		City          string
+		LikesStarTrek bool
+		Age           int32
+	}
+
+	rows := newBenchmarkPersonRows()
+	rows.Next()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := pgxtras.RowToStructBySnakeToCamelName[person](rows); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// TestRowToStructConcurrent scans rows from many goroutines at once, with
+// two different column orders, so that run with -race, it checks that the
+// shared scan plan cache is safe for concurrent use.
+func TestRowToStructConcurrent(t *testing.T) {
+	type person struct {
+		ID        int32
+		FirstName string
+		LastName  string
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				var rows *staticRows
+				if (g+i)%2 == 0 {
+					rows = newStaticRows([]string{"id", "first_name", "last_name"}, []uint32{pgtype.Int4OID, pgtype.TextOID, pgtype.TextOID}, []string{"1", "John", "Smith"})
+				} else {
+					rows = newStaticRows([]string{"last_name", "id", "first_name"}, []uint32{pgtype.TextOID, pgtype.Int4OID, pgtype.TextOID}, []string{"Smith", "1", "John"})
+				}
+				p, err := pgx.CollectOneRow[person](rows, pgxtras.RowToStructBySimpleName[person])
+				if !assert.NoError(t, err) {
+					return
+				}
+				if !assert.Equal(t, person{ID: 1, FirstName: "John", LastName: "Smith"}, p) {
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func TestRowToStructWith(t *testing.T) {
+	type person struct {
+		ID        int32
+		FirstName string
+		LastName  string `db:"surname"`
+	}
+
+	tblPrefixMapper := pgxtras.NameMapperFunc(func(columnName, fieldName string) bool {
+		return pgxtras.SimpleNameMapper.Match(strings.TrimPrefix(columnName, "tbl_"), fieldName)
+	})
+	rowToPerson := pgxtras.RowToStructWith[person](tblPrefixMapper)
+	rowToAddrOfPerson := pgxtras.RowToAddrOfStructWith[person](tblPrefixMapper)
+
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		rows, _ := conn.Query(ctx, `
+select n       as tbl_id,
+       'John'  as tbl_first_name,
+       'Smith' as tbl_surname
+  from generate_series(0, 9) n`)
+		slice, err := pgx.CollectRows(rows, rowToPerson)
+		require.NoError(t, err)
+
+		assert.Len(t, slice, 10)
+		for i := range slice {
+			assert.EqualValues(t, i, slice[i].ID)
+			assert.Equal(t, "John", slice[i].FirstName)
+			assert.Equal(t, "Smith", slice[i].LastName)
+		}
+
+		rows, _ = conn.Query(ctx, `select 1 as tbl_id, 'John' as tbl_first_name, 'Smith' as tbl_surname`)
+		p, err := pgx.CollectOneRow(rows, rowToAddrOfPerson)
+		require.NoError(t, err)
+		assert.Equal(t, &person{ID: 1, FirstName: "John", LastName: "Smith"}, p)
+
+		// check missing fields in a returned row
+		rows, _ = conn.Query(ctx, `select 1 as tbl_id, 'Smith' as tbl_surname`)
+		_, err = pgx.CollectRows(rows, rowToPerson)
+		assert.ErrorContains(t, err, "no column in returned row matches struct field FirstName")
+	})
+}
+
+func TestRowToStructBySimpleNameLax(t *testing.T) {
+	type person struct {
+		ID        int
+		FirstName string
+		LastName  string
+		Age       int32
+	}
+
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		// first_name and age have no column; ignore has no field.
+		rows, _ := conn.Query(ctx, `
+select n       as id,
+       'Smith' as last_name,
+       null    as ignore
+  from generate_series(0, 9) n`)
+		slice, err := pgx.CollectRows(rows, pgxtras.RowToStructBySimpleNameLax[person])
+		require.NoError(t, err)
+
+		assert.Len(t, slice, 10)
+		for i := range slice {
+			assert.Equal(t, person{ID: i, LastName: "Smith"}, slice[i])
+		}
+
+		rows, _ = conn.Query(ctx, `select 1 as id, 'John' as first_name, 42 as age`)
+		p, err := pgx.CollectOneRow(rows, pgxtras.RowToAddrOfStructBySimpleNameLax[person])
+		require.NoError(t, err)
+		assert.Equal(t, &person{ID: 1, FirstName: "John", Age: 42}, p)
+	})
+}
+
+func TestRowToStructWithReportAllMismatches(t *testing.T) {
+	type person struct {
+		ID        int
+		FirstName string
+		LastName  string
+	}
+
+	rowToPerson := pgxtras.RowToStructWith[person](pgxtras.SimpleNameMapper, pgxtras.ReportAllMismatches())
+
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		rows, _ := conn.Query(ctx, `select 1 as id, null as ignore, null as also_ignore`)
+		_, err := pgx.CollectRows(rows, rowToPerson)
+		assert.ErrorContains(t, err, "4 mismatches")
+		assert.ErrorContains(t, err, "no column in returned row matches struct field FirstName")
+		assert.ErrorContains(t, err, "no column in returned row matches struct field LastName")
+		assert.ErrorContains(t, err, "struct doesn't have corresponding field to match returned column ignore")
+		assert.ErrorContains(t, err, "struct doesn't have corresponding field to match returned column also_ignore")
+
+		// A single mismatch reads the same as it does without ReportAllMismatches.
+		rows, _ = conn.Query(ctx, `select 1 as id, 'John' as first_name`)
+		_, err = pgx.CollectRows(rows, rowToPerson)
+		assert.EqualError(t, err, "no column in returned row matches struct field LastName")
+	})
+}
+
+func TestRowToStructBySimpleNameEmbeddedPointer(t *testing.T) {
+	type Base struct {
+		ID int
+	}
+
+	type person struct {
+		*Base
+		FirstName string
+	}
+
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		rows, _ := conn.Query(ctx, `select n as id, 'John' as first_name from generate_series(0, 9) n`)
+		slice, err := pgx.CollectRows(rows, pgxtras.RowToStructBySimpleName[person])
+		require.NoError(t, err)
+
+		assert.Len(t, slice, 10)
+		for i := range slice {
+			require.NotNil(t, slice[i].Base)
+			assert.Equal(t, i, slice[i].ID)
+			assert.Equal(t, "John", slice[i].FirstName)
+		}
+
+		// With lax matching, an embedded pointer that no column is scanned into stays nil.
+		rows, _ = conn.Query(ctx, `select 'John' as first_name`)
+		p, err := pgx.CollectOneRow(rows, pgxtras.RowToStructBySimpleNameLax[person])
+		require.NoError(t, err)
+		assert.Nil(t, p.Base)
+		assert.Equal(t, "John", p.FirstName)
+	})
+}
+
+func TestRowToStructBySimpleNameInlineStruct(t *testing.T) {
+	type Address struct {
+		Street string
+		City   string
+	}
+
+	type person struct {
+		Name    string
+		Address Address  `db:",prefix=addr_"`
+		Home    *Address `db:"home,inline"`
+		Extra   struct {
+			Age int32
+		} `db:",inline"`
+	}
+
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		rows, _ := conn.Query(ctx, `
+select 'John'        as name,
+       'Main St'     as addr_street,
+       'Ottawa'      as addr_city,
+       'Elm St'      as home_street,
+       'Kingston'    as home_city,
+       42            as age`)
+		p, err := pgx.CollectOneRow(rows, pgxtras.RowToStructBySimpleName[person])
+		require.NoError(t, err)
+		assert.Equal(t, "John", p.Name)
+		assert.Equal(t, Address{Street: "Main St", City: "Ottawa"}, p.Address)
+		assert.Equal(t, &Address{Street: "Elm St", City: "Kingston"}, p.Home)
+		assert.EqualValues(t, 42, p.Extra.Age)
+
+		// check missing fields of a nested struct in a returned row
+		rows, _ = conn.Query(ctx, `
+select 'John'        as name,
+       'Main St'     as addr_street,
+       'Elm St'      as home_street,
+       'Kingston'    as home_city,
+       42            as age`)
+		_, err = pgx.CollectOneRow(rows, pgxtras.RowToStructBySimpleName[person])
+		assert.ErrorContains(t, err, "no column in returned row matches struct field Address.City")
+	})
+}
+
+func TestRowToStructBySimpleNameAmbiguous(t *testing.T) {
+	type person struct {
+		UserID int32
+		Name   string
+	}
+
+	type twoIDs struct {
+		UserID  int32
+		User_ID int32
+		Name    string
+	}
+
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		// two columns matching the same field
+		rows, _ := conn.Query(ctx, `select 1 as user_id, 2 as userid, 'John' as name`)
+		_, err := pgx.CollectRows(rows, pgxtras.RowToStructBySimpleName[person])
+		assert.EqualError(t, err, "struct field UserID matches both returned column user_id and returned column userid")
+		var mappingErr *pgxtras.MappingError
+		require.True(t, errors.As(err, &mappingErr))
+		assert.Equal(t, pgxtras.AmbiguousColumn, mappingErr.Kind)
+
+		// duplicate columns, even when lax
+		rows, _ = conn.Query(ctx, `select 1 as user_id, 2 as user_id, 'John' as name`)
+		_, err = pgx.CollectRows(rows, pgxtras.RowToStructBySimpleNameLax[person])
+		assert.EqualError(t, err, "struct field UserID matches both returned column user_id and returned column user_id")
+
+		// two fields matching the same column
+		rows, _ = conn.Query(ctx, `select 1 as user_id, 'John' as name`)
+		_, err = pgx.CollectRows(rows, pgxtras.RowToStructBySimpleName[twoIDs])
+		assert.EqualError(t, err, "returned column user_id matches both struct field UserID and struct field User_ID")
+	})
+}
+
+func TestRowToStructWithPreferExactNames(t *testing.T) {
+	type person struct {
+		UserID int32
+		Name   string
+	}
+
+	type twoIDs struct {
+		UserID  int32
+		User_ID int32 `db:"user_id"`
+		Name    string
+	}
+
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		rows, _ := conn.Query(ctx, `select 1 as user_id, 2 as userid, 'John' as name`)
+		p, err := pgx.CollectOneRow(rows, pgxtras.RowToStructWith[person](pgxtras.SimpleNameMapper, pgxtras.PreferExactNames(), pgxtras.LaxMatching()))
+		require.NoError(t, err)
+		assert.Equal(t, person{UserID: 2, Name: "John"}, p)
+
+		// Without LaxMatching, the column passed over isn't an UnmappedColumn error.
+		rows, _ = conn.Query(ctx, `select 1 as user_id, 2 as userid, 'John' as name`)
+		p, err = pgx.CollectOneRow(rows, pgxtras.RowToStructWith[person](pgxtras.SimpleNameMapper, pgxtras.PreferExactNames()))
+		require.NoError(t, err)
+		assert.Equal(t, person{UserID: 2, Name: "John"}, p)
+
+		// A column that matches no field is still one.
+		rows, _ = conn.Query(ctx, `select 1 as user_id, 2 as userid, 'John' as name, 3 as age`)
+		_, err = pgx.CollectOneRow(rows, pgxtras.RowToStructWith[person](pgxtras.SimpleNameMapper, pgxtras.PreferExactNames()))
+		var mappingErr *pgxtras.MappingError
+		require.True(t, errors.As(err, &mappingErr))
+		assert.Equal(t, pgxtras.UnmappedColumn, mappingErr.Kind)
+		assert.Equal(t, "age", mappingErr.ColumnName)
+
+		rows, _ = conn.Query(ctx, `select 1 as user_id, 'John' as name`)
+		ids, err := pgx.CollectOneRow(rows, pgxtras.RowToStructWith[twoIDs](pgxtras.SimpleNameMapper, pgxtras.PreferExactNames(), pgxtras.LaxMatching()))
+		require.NoError(t, err)
+		assert.Equal(t, twoIDs{User_ID: 1, Name: "John"}, ids)
+	})
+}
+
+func TestRowToStructBySimpleNameEmbeddedStructShadowing(t *testing.T) {
+	type Base struct {
+		ID   int32
+		Name string
+	}
+
+	type person struct {
+		Base
+		ID int32
+	}
+
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		// As with Go field promotion, the outer ID wins over the embedded one.
+		rows, _ := conn.Query(ctx, `select 1 as id, 'John' as name`)
+		p, err := pgx.CollectOneRow(rows, pgxtras.RowToStructBySimpleName[person])
+		require.NoError(t, err)
+		assert.Equal(t, person{Base: Base{Name: "John"}, ID: 1}, p)
+	})
+}
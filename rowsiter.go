@@ -0,0 +1,74 @@
+//go:build go1.23
+
+package pgxtras
+
+import (
+	"iter"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Rows returns an iterator over rows that scans each row using fn, so that
+// a large result can be worked through a row at a time rather than collected
+// into a slice first:
+//
+//	rows, _ := conn.Query(ctx, `select * from users`)
+//	for user, err := range pgxtras.Rows(rows, pgxtras.RowToStructBySimpleName[User]) {
+//		if err != nil {
+//			return err
+//		}
+//		// ...
+//	}
+//
+// If fn returns an error, the iterator yields it and stops. If rows.Err()
+// returns an error once the rows are used up, the iterator yields it, along
+// with the zero value of T, at the end. rows is closed when the iterator
+// stops, including when the loop over it is broken out of early.
+func Rows[T any](rows pgx.Rows, fn pgx.RowToFunc[T]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		defer rows.Close()
+
+		for rows.Next() {
+			value, err := fn(rows)
+			if err != nil {
+				yield(value, err)
+				return
+			}
+			if !yield(value, nil) {
+				return
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
+
+// RowsSeq is Rows for those who would rather check for an error once, after
+// the loop, in the style of bufio.Scanner. The iterator stops at the first
+// error, which the returned function then returns.
+//
+//	users, errf := pgxtras.RowsSeq(rows, pgxtras.RowToStructBySimpleName[User])
+//	for user := range users {
+//		// ...
+//	}
+//	if err := errf(); err != nil {
+//		return err
+//	}
+func RowsSeq[T any](rows pgx.Rows, fn pgx.RowToFunc[T]) (iter.Seq[T], func() error) {
+	var iterErr error
+	seq := func(yield func(T) bool) {
+		for value, err := range Rows(rows, fn) {
+			if err != nil {
+				iterErr = err
+				return
+			}
+			if !yield(value) {
+				return
+			}
+		}
+	}
+	return seq, func() error { return iterErr }
+}
@@ -0,0 +1,81 @@
+//go:build go1.23
+
+package pgxtras_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/manniwood/pgxtras"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRows(t *testing.T) {
+	type person struct {
+		ID   int32
+		Name string
+	}
+
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		rows, _ := conn.Query(ctx, `select n as id, 'Joe' as name from generate_series(1, 10) n`)
+		var ids []int32
+		for p, err := range pgxtras.Rows(rows, pgxtras.RowToStructBySimpleName[person]) {
+			require.NoError(t, err)
+			assert.Equal(t, "Joe", p.Name)
+			ids = append(ids, p.ID)
+		}
+		assert.Equal(t, []int32{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, ids)
+
+		// breaking out early closes rows, leaving the conn free for the next query
+		rows, _ = conn.Query(ctx, `select n as id, 'Joe' as name from generate_series(1, 10) n`)
+		for p, err := range pgxtras.Rows(rows, pgxtras.RowToStructBySimpleName[person]) {
+			require.NoError(t, err)
+			if p.ID == 3 {
+				break
+			}
+		}
+		var n int32
+		require.NoError(t, conn.QueryRow(ctx, `select 1`).Scan(&n))
+
+		// a scanning error stops the iteration
+		rows, _ = conn.Query(ctx, `select n as id from generate_series(1, 10) n`)
+		count := 0
+		for _, err := range pgxtras.Rows(rows, pgxtras.RowToStructBySimpleName[person]) {
+			assert.Error(t, err)
+			count++
+		}
+		assert.Equal(t, 1, count)
+
+		// so does an error from the query
+		rows, _ = conn.Query(ctx, `select 10 / (5 - n) as id, 'Joe' as name from generate_series(1, 10) n`)
+		var lastErr error
+		for _, err := range pgxtras.Rows(rows, pgxtras.RowToStructBySimpleName[person]) {
+			lastErr = err
+		}
+		assert.ErrorContains(t, lastErr, "division by zero")
+	})
+}
+
+func TestRowsSeq(t *testing.T) {
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		rows, _ := conn.Query(ctx, `select n from generate_series(1, 5) n`)
+		seq, errf := pgxtras.RowsSeq(rows, pgx.RowTo[int32])
+		var sum int32
+		for n := range seq {
+			sum += n
+		}
+		require.NoError(t, errf())
+		assert.Equal(t, int32(15), sum)
+
+		rows, _ = conn.Query(ctx, `select 10 / (3 - n) from generate_series(1, 5) n`)
+		seq, errf = pgxtras.RowsSeq(rows, pgx.RowTo[int32])
+		count := 0
+		for range seq {
+			count++
+		}
+		assert.ErrorContains(t, errf(), "division by zero")
+		assert.Equal(t, 2, count)
+	})
+}
@@ -0,0 +1,534 @@
+package pgxtras
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// structScanPlan records, for each column of a result set, the index path
+// (suitable for reflect.Value.FieldByIndex) of the struct field that the
+// column is scanned into. Building a plan is the expensive part of struct
+// scanning (reflection over every field, and name translation of every
+// column), so plans are built once per struct type and column list and then
+// reused for every row.
+//
+// A plan may also scan a row into several structs, in which case structPos
+// records which struct each column is scanned into.
+type structScanPlan struct {
+	columnNames  []string
+	structTypes  []reflect.Type
+	structPos    []int
+	fieldIndexes [][]int
+	fieldNames   []string
+}
+
+// matches reports whether the plan was built for the given columns.
+func (p *structScanPlan) matches(fldDescs []pgconn.FieldDescription) bool {
+	if len(p.columnNames) != len(fldDescs) {
+		return false
+	}
+	for i := range fldDescs {
+		if p.columnNames[i] != fldDescs[i].Name {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesTypes reports whether the plan was built for the given struct types.
+func (p *structScanPlan) matchesTypes(structTypes []reflect.Type) bool {
+	if len(p.structTypes) != len(structTypes) {
+		return false
+	}
+	for i := range structTypes {
+		if p.structTypes[i] != structTypes[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// columnStructType returns the type of the struct that column i is scanned into.
+func (p *structScanPlan) columnStructType(i int) reflect.Type {
+	if p.structPos == nil {
+		return p.structTypes[0]
+	}
+	if p.structPos[i] < 0 {
+		return nil
+	}
+	return p.structTypes[p.structPos[i]]
+}
+
+// scanTargets returns pointers to the fields of dstElemValues, in column order.
+// Columns that have no field get a nil scan target, which rows.Scan skips.
+func (p *structScanPlan) scanTargets(dstElemValues ...reflect.Value) []any {
+	scanTargets := make([]any, len(p.fieldIndexes))
+	for i, index := range p.fieldIndexes {
+		if index == nil {
+			continue
+		}
+		dstElemValue := dstElemValues[0]
+		if p.structPos != nil {
+			dstElemValue = dstElemValues[p.structPos[i]]
+		}
+		scanTargets[i] = fieldByIndexAlloc(dstElemValue, index).Addr().Interface()
+	}
+	return scanTargets
+}
+
+// fieldByIndexAlloc is like reflect.Value.FieldByIndex, except that rather
+// than panicking when it comes across a nil pointer to a struct along the way,
+// it allocates the struct. That way, embedded and inline pointers to structs
+// are only allocated when there is a column to scan into them.
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+type structScanPlanKey struct {
+	structType  reflect.Type
+	columnsHash uint64
+}
+
+// structScanPlanCache holds the scan plans built using one particular
+// NameMapper and set of StructScanOptions. It is safe for concurrent use.
+type structScanPlanCache struct {
+	mapper      NameMapper
+	lax         bool
+	reportAll   bool
+	preferExact bool
+	plans       sync.Map // structScanPlanKey -> *structScanPlan
+}
+
+func newStructScanPlanCache(mapper NameMapper, opts ...StructScanOption) *structScanPlanCache {
+	c := &structScanPlanCache{mapper: mapper}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// plan returns the scan plan for structType and fldDescs, building
+// and caching it if this combination has not been seen before.
+func (c *structScanPlanCache) plan(structType reflect.Type, fldDescs []pgconn.FieldDescription) (*structScanPlan, error) {
+	key := structScanPlanKey{structType: structType, columnsHash: hashColumnNames(fldDescs)}
+	cached, ok := c.plans.Load(key)
+	if ok && cached.(*structScanPlan).matches(fldDescs) {
+		return cached.(*structScanPlan), nil
+	}
+
+	plan, err := c.buildPlan([]reflect.Type{structType}, ColumnSplit{}, fldDescs)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// On the (vanishingly unlikely) event of a hash collision, the plan
+		// that got there first keeps the slot and the other is rebuilt on
+		// every call; that is slower but still correct.
+		c.plans.LoadOrStore(key, plan)
+	}
+	return plan, nil
+}
+
+// multiPlan is plan for scanning fldDescs into several structs, split
+// between them by split. It is keyed on the first of structTypes only,
+// and the rest are checked, because a cache is normally used with only
+// the one list of struct types.
+func (c *structScanPlanCache) multiPlan(structTypes []reflect.Type, split ColumnSplit, fldDescs []pgconn.FieldDescription) (*structScanPlan, error) {
+	key := structScanPlanKey{structType: structTypes[0], columnsHash: hashColumnNames(fldDescs)}
+	cached, ok := c.plans.Load(key)
+	if ok && cached.(*structScanPlan).matches(fldDescs) && cached.(*structScanPlan).matchesTypes(structTypes) {
+		return cached.(*structScanPlan), nil
+	}
+
+	plan, err := c.buildPlan(structTypes, split, fldDescs)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		c.plans.LoadOrStore(key, plan)
+	}
+	return plan, nil
+}
+
+func (c *structScanPlanCache) buildPlan(structTypes []reflect.Type, split ColumnSplit, fldDescs []pgconn.FieldDescription) (*structScanPlan, error) {
+	plan, mismatches := c.matchColumns(structTypes, split, fldDescs)
+	switch {
+	case len(mismatches) == 0:
+		return plan, nil
+	case !c.reportAll || len(mismatches) == 1:
+		return nil, mismatches[0]
+	default:
+		return nil, mismatches
+	}
+}
+
+// matchColumns matches fldDescs to the fields of structTypes, returning the
+// plan for scanning them, along with everything that didn't match. The plan
+// is only usable if nothing is returned with it, but even when something is,
+// it says which columns did match which fields, or it is nil if structTypes
+// aren't all structs.
+func (c *structScanPlanCache) matchColumns(structTypes []reflect.Type, split ColumnSplit, fldDescs []pgconn.FieldDescription) (*structScanPlan, MappingErrors) {
+	for _, structType := range structTypes {
+		if structType.Kind() != reflect.Struct {
+			return nil, MappingErrors{&MappingError{Kind: NotAStruct, StructType: structType, ColumnIndex: -1}}
+		}
+	}
+
+	plan := &structScanPlan{
+		columnNames:  make([]string, len(fldDescs)),
+		structTypes:  structTypes,
+		fieldIndexes: make([][]int, len(fldDescs)),
+		fieldNames:   make([]string, len(fldDescs)),
+	}
+	for i := range fldDescs {
+		plan.columnNames[i] = fldDescs[i].Name
+	}
+
+	if split.prefixes != nil || split.positions != nil {
+		plan.structPos = split.structPos(fldDescs)
+	}
+	var mismatches MappingErrors
+	exact := make([]bool, len(fldDescs))
+	passedOver := make([]bool, len(fldDescs))
+	for pos, structType := range structTypes {
+		b := structScanPlanBuilder{
+			mapper:       c.mapper,
+			preferExact:  c.preferExact,
+			fldDescs:     fldDescs,
+			structType:   structType,
+			structPos:    plan.structPos,
+			pos:          pos,
+			fieldIndexes: plan.fieldIndexes,
+			fieldNames:   plan.fieldNames,
+			exact:        exact,
+			passedOver:   passedOver,
+		}
+		mismatches = b.appendFieldIndexes(structType, nil, split.prefix(pos), "", mismatches)
+	}
+	if c.lax {
+		// Ambiguity is still an error, because there is no telling
+		// which of the ambiguous matches was meant.
+		var ambiguities MappingErrors
+		for _, err := range mismatches {
+			if err.Kind == AmbiguousColumn {
+				ambiguities = append(ambiguities, err)
+			}
+		}
+		mismatches = ambiguities
+	}
+
+	for i, index := range plan.fieldIndexes {
+		// A column that PreferExactNames passed over for an exact match
+		// was matched, just not chosen, so it isn't reported as unmapped.
+		if index == nil && !c.lax && !passedOver[i] {
+			mismatches = append(mismatches, &MappingError{
+				Kind:        UnmappedColumn,
+				StructType:  plan.columnStructType(i),
+				ColumnName:  fldDescs[i].Name,
+				ColumnIndex: i,
+			})
+		}
+	}
+
+	return plan, mismatches
+}
+
+// structScanPlanBuilder matches the fields of a struct against columns.
+// When a row is being split between several structs, it matches only the
+// columns whose structPos is pos.
+type structScanPlanBuilder struct {
+	mapper       NameMapper
+	preferExact  bool
+	fldDescs     []pgconn.FieldDescription
+	structType   reflect.Type
+	structPos    []int
+	pos          int
+	fieldIndexes [][]int
+	fieldNames   []string
+	// exact records, for each column, whether it matched its field by exact name.
+	exact []bool
+	// passedOver records, for each column, whether it matched a field, but
+	// PreferExactNames picked another column that matched it exactly.
+	passedOver []bool
+}
+
+// appendFieldIndexes fills in b.fieldIndexes with the index paths of the fields of
+// structType that match b.fldDescs, and returns mismatches with a MissingColumn
+// error appended to it for each field that matches no column, and an
+// AmbiguousColumn error for each field or column that matches more than once.
+//
+// Fields of embedded structs, and of nested structs tagged with the "inline"
+// or "prefix=" options, are treated as fields of the outer struct. Column
+// names must start with colPrefix to match them, and fieldPathPrefix is put
+// in front of their names when reporting them as unmatched.
+func (b *structScanPlanBuilder) appendFieldIndexes(structType reflect.Type, parentIndex []int, colPrefix string, fieldPathPrefix string, mismatches MappingErrors) MappingErrors {
+	for i := 0; i < structType.NumField(); i++ {
+		sf := structType.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			// Field is unexported, skip it.
+			continue
+		}
+		tag := parseDBTag(sf)
+		if tag.name == "-" {
+			// Field is ignored, skip it.
+			continue
+		}
+		index := make([]int, len(parentIndex)+1)
+		copy(index, parentIndex)
+		index[len(parentIndex)] = i
+
+		if nestedType, ok := embeddedOrInlineStruct(sf, tag); ok {
+			// Flatten the struct's fields into this one. Only anonymous embedded
+			// structs are flattened without being asked to be, because a named
+			// struct field may well be something (like time.Time) that pgx
+			// scans into as a single value.
+			nestedFieldPathPrefix := fieldPathPrefix
+			if !sf.Anonymous {
+				nestedFieldPathPrefix += sf.Name + "."
+			}
+			mismatches = b.appendFieldIndexes(nestedType, index, colPrefix+tag.prefix, nestedFieldPathPrefix, mismatches)
+			continue
+		}
+		if sf.PkgPath != "" {
+			// Field is an embedded unexported type that cannot be set from
+			// outside of its package (other than a struct, whose exported
+			// fields were handled above); skip it.
+			continue
+		}
+
+		colName := tag.name
+		if colName == "" {
+			colName = sf.Name
+		}
+		fieldName := fieldPathPrefix + colName
+		fpos, err := b.fieldPos(colPrefix, colName, fieldName)
+		if err != nil {
+			mismatches = append(mismatches, err)
+			continue
+		}
+		if fpos == -1 || fpos >= len(b.fieldIndexes) {
+			mismatches = append(mismatches, &MappingError{
+				Kind:        MissingColumn,
+				StructType:  b.structType,
+				FieldName:   fieldName,
+				ColumnIndex: -1,
+			})
+			continue
+		}
+		exact := isExactMatch(b.fldDescs[fpos].Name, colPrefix, colName)
+		if b.fieldIndexes[fpos] != nil {
+			// Another field already matches this column. As with Go's own
+			// promotion of the fields of embedded structs, a field nested less
+			// deeply wins over one nested more deeply.
+			switch {
+			case len(index) > len(b.fieldIndexes[fpos]):
+				// The other field wins.
+				continue
+			case len(index) < len(b.fieldIndexes[fpos]):
+				// This field wins.
+			case b.preferExact && exact && !b.exact[fpos]:
+				// This field wins.
+			case b.preferExact && !exact && b.exact[fpos]:
+				// The other field wins.
+				continue
+			default:
+				mismatches = append(mismatches, &MappingError{
+					Kind:           AmbiguousColumn,
+					StructType:     b.structType,
+					FieldName:      b.fieldNames[fpos],
+					OtherFieldName: fieldName,
+					ColumnName:     b.fldDescs[fpos].Name,
+					ColumnIndex:    fpos,
+				})
+				continue
+			}
+		}
+		b.fieldIndexes[fpos] = index
+		b.fieldNames[fpos] = fieldName
+		b.exact[fpos] = exact
+	}
+	return mismatches
+}
+
+// embeddedOrInlineStruct returns the struct type whose fields are to be
+// flattened into those of the struct containing sf, if any. That is the case
+// for an embedded struct or embedded pointer to a struct, and for any other
+// struct or pointer to a struct whose "db" tag has the "inline" or "prefix="
+// option.
+func embeddedOrInlineStruct(sf reflect.StructField, tag dbTag) (reflect.Type, bool) {
+	if !sf.Anonymous && !tag.inline {
+		return nil, false
+	}
+	t := sf.Type
+	if t.Kind() == reflect.Ptr {
+		if sf.PkgPath != "" {
+			return nil, false
+		}
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, false
+	}
+	return t, true
+}
+
+// dbTag is the parsed form of a "db" struct tag such as
+//
+//	`db:"name"`, `db:"-"`, `db:"address,inline"`, `db:",prefix=addr_"` or `db:"id,readonly"`
+type dbTag struct {
+	// name is the column name, or "-" if the field is to be ignored,
+	// or "" if the tag is absent or gives no name.
+	name string
+	// inline is set by either the "inline" option or the "prefix=" option,
+	// and says that the fields of a nested struct are to be matched as
+	// though they were fields of the outer struct.
+	inline bool
+	// prefix is the column name prefix that the nested struct's fields
+	// must have. It is given by the "prefix=" option, or else is the
+	// tag's name followed by an underscore when "inline" is given along
+	// with a name.
+	prefix string
+	// omitEmpty and readOnly are set by the "omitempty" and "readonly"
+	// options, which only matter when writing structs to the database:
+	// an omitempty field is left out of an INSERT when it has its zero
+	// value, and a readonly field (such as a serial ID or a generated
+	// column) is never written at all.
+	omitEmpty bool
+	readOnly  bool
+}
+
+func parseDBTag(sf reflect.StructField) dbTag {
+	var tag dbTag
+	value, ok := sf.Tag.Lookup(structTagKey)
+	if !ok {
+		return tag
+	}
+	parts := strings.Split(value, ",")
+	tag.name = parts[0]
+	hasPrefix := false
+	for _, opt := range parts[1:] {
+		switch {
+		case opt == "inline":
+			tag.inline = true
+		case opt == "omitempty":
+			tag.omitEmpty = true
+		case opt == "readonly":
+			tag.readOnly = true
+		case strings.HasPrefix(opt, "prefix="):
+			tag.inline = true
+			tag.prefix = strings.TrimPrefix(opt, "prefix=")
+			hasPrefix = true
+		}
+	}
+	if tag.inline && !hasPrefix && tag.name != "" {
+		tag.prefix = tag.name + "_"
+	}
+	return tag
+}
+
+// fieldPos returns the position of the column that has colPrefix (compared
+// case-insensitively) and whose name, with colPrefix removed, matches field.
+// It returns -1 if there is no such column, and an AmbiguousColumn error if
+// there is more than one, unless b.preferExact is set and exactly one of them
+// is an exact match. fieldName is the name of the field to use in the error.
+func (b *structScanPlanBuilder) fieldPos(colPrefix string, field string, fieldName string) (int, *MappingError) {
+	fpos := -1
+	exactPos := -1
+	var matched []int
+	var ambiguity *MappingError
+	for i, desc := range b.fldDescs {
+		if b.structPos != nil && b.structPos[i] != b.pos {
+			continue
+		}
+		name, ok := trimPrefixFold(desc.Name, colPrefix)
+		if !ok || !b.mapper.Match(name, field) {
+			continue
+		}
+		if b.preferExact && strings.EqualFold(name, field) {
+			if exactPos != -1 {
+				return -1, newFieldAmbiguity(b.structType, fieldName, b.fldDescs, exactPos, i)
+			}
+			exactPos = i
+		}
+		matched = append(matched, i)
+		if fpos == -1 {
+			fpos = i
+		} else if ambiguity == nil {
+			ambiguity = newFieldAmbiguity(b.structType, fieldName, b.fldDescs, fpos, i)
+		}
+	}
+	if exactPos != -1 {
+		for _, i := range matched {
+			if i != exactPos {
+				b.passedOver[i] = true
+			}
+		}
+		return exactPos, nil
+	}
+	if ambiguity != nil {
+		return -1, ambiguity
+	}
+	return fpos, nil
+}
+
+// newFieldAmbiguity returns the error for a field that matches both column
+// i and column j.
+func newFieldAmbiguity(structType reflect.Type, fieldName string, fldDescs []pgconn.FieldDescription, i, j int) *MappingError {
+	return &MappingError{
+		Kind:            AmbiguousColumn,
+		StructType:      structType,
+		FieldName:       fieldName,
+		ColumnName:      fldDescs[i].Name,
+		ColumnIndex:     i,
+		OtherColumnName: fldDescs[j].Name,
+	}
+}
+
+// isExactMatch reports whether column columnName, once colPrefix is removed,
+// is exactly (apart from case) field.
+func isExactMatch(columnName string, colPrefix string, field string) bool {
+	name, _ := trimPrefixFold(columnName, colPrefix)
+	return strings.EqualFold(name, field)
+}
+
+// trimPrefixFold returns s without prefix, which is compared case-insensitively,
+// and whether s had prefix.
+func trimPrefixFold(s, prefix string) (string, bool) {
+	if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
+
+// hashColumnNames is FNV-1a over the column names, written out by hand so
+// that looking up a cached plan does not allocate.
+func hashColumnNames(fldDescs []pgconn.FieldDescription) uint64 {
+	const (
+		offset64 = 14695981039346656037
+		prime64  = 1099511628211
+	)
+	h := uint64(offset64)
+	for i := range fldDescs {
+		name := fldDescs[i].Name
+		for j := 0; j < len(name); j++ {
+			h ^= uint64(name[j])
+			h *= prime64
+		}
+		// Hash a zero byte between names so that ("ab", "c")
+		// and ("a", "bc") differ.
+		h *= prime64
+	}
+	return h
+}
@@ -0,0 +1,147 @@
+package pgxtras
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TxOption adjusts how WithTx retries a transaction.
+type TxOption func(*txConfig)
+
+type txConfig struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+// MaxAttempts sets the most times WithTx runs a transaction, including the
+// first time. The default is 3. MaxAttempts(1) turns off retrying.
+func MaxAttempts(n int) TxOption {
+	return func(c *txConfig) {
+		c.maxAttempts = n
+	}
+}
+
+// RetryBackoff sets how long WithTx waits before retrying a transaction:
+// about base before the first retry, doubling before each retry after
+// that, but never more than ceiling. The defaults are 10ms and 1s.
+func RetryBackoff(base, ceiling time.Duration) TxOption {
+	return func(c *txConfig) {
+		c.baseDelay = base
+		c.maxDelay = ceiling
+	}
+}
+
+// delay returns how long to wait before the given retry (counting from 1).
+// The wait is picked at random from the upper half of the backoff, so that
+// transactions that failed together don't retry together.
+func (c *txConfig) delay(retry int) time.Duration {
+	d := c.baseDelay
+	for i := 1; i < retry && d < c.maxDelay; i++ {
+		d *= 2
+	}
+	if d > c.maxDelay {
+		d = c.maxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// WithTx runs fn in a transaction begun on db with txOptions, committing it
+// if fn returns nil, and rolling it back if fn returns an error or panics.
+// db may be a pgx.Conn, pgxpool.Pool or pgxpool.Conn.
+//
+// If txOptions asks for the repeatable read or serializable isolation level,
+// and the transaction fails with a serialization failure or a deadlock
+// (SQLSTATE 40001 or 40P01), which are what those isolation levels expect you
+// to retry, or with any other error that IsRetryable says can be retried,
+// WithTx backs off and runs the whole transaction again, up to the limit set
+// by MaxAttempts. So fn may be called more than once, and should
+// do nothing outside the transaction that can't be done twice.
+//
+// db may also be a pgx.Tx or pgxpool.Tx, in which case fn is run in a
+// savepoint, which is released if fn returns nil and rolled back to otherwise,
+// so that functions that use WithTx can be nested inside one another.
+// txOptions and retrying then belong to the outermost transaction, and are
+// ignored.
+//
+//	err := pgxtras.WithTx(ctx, pool, pgx.TxOptions{IsoLevel: pgx.Serializable}, func(tx pgx.Tx) error {
+//		_, err := tx.Exec(ctx, `update accounts set balance = balance - $1 where id = $2`, amount, from)
+//		if err != nil {
+//			return err
+//		}
+//		_, err = tx.Exec(ctx, `update accounts set balance = balance + $1 where id = $2`, amount, to)
+//		return err
+//	})
+func WithTx(ctx context.Context, db Beginner, txOptions pgx.TxOptions, fn func(pgx.Tx) error, opts ...TxOption) error {
+	if tx, ok := db.(pgx.Tx); ok {
+		return pgx.BeginFunc(ctx, tx, fn)
+	}
+	txDB, ok := db.(interface {
+		BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+	})
+	if !ok {
+		if txOptions != (pgx.TxOptions{}) {
+			return fmt.Errorf("can't begin a transaction with options on a %T", db)
+		}
+		return pgx.BeginFunc(ctx, db, fn)
+	}
+
+	c := txConfig{maxAttempts: 3, baseDelay: 10 * time.Millisecond, maxDelay: time.Second}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	retryable := txOptions.IsoLevel == pgx.RepeatableRead || txOptions.IsoLevel == pgx.Serializable
+
+	for attempt := 1; ; attempt++ {
+		err := pgx.BeginTxFunc(ctx, txDB, txOptions, fn)
+		if err == nil || !retryable || attempt >= c.maxAttempts || !IsRetryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(c.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// Nested runs fn in a transaction of its own if q is a connection or pool (as
+// pgx.Conn, pgxpool.Pool and pgxpool.Conn are), or in a savepoint if q is
+// already a transaction (as pgx.Tx and pgxpool.Tx are). Either way, what fn
+// does is committed (or the savepoint released) if fn returns nil, and is
+// rolled back if fn returns an error or panics; but in a savepoint, the rest
+// of the transaction carries on regardless. That way, a data access function
+// that takes a QuerierExecer can make its changes all or nothing without
+// knowing whether its caller has begun a transaction.
+//
+//	func transfer(ctx context.Context, q pgxtras.QuerierExecer, amount int64, from, to int32) error {
+//		return pgxtras.Nested(ctx, q, func(q pgxtras.QuerierExecer) error {
+//			_, err := q.Exec(ctx, `update accounts set balance = balance - $1 where id = $2`, amount, from)
+//			if err != nil {
+//				return err
+//			}
+//			_, err = q.Exec(ctx, `update accounts set balance = balance + $1 where id = $2`, amount, to)
+//			return err
+//		})
+//	}
+func Nested(ctx context.Context, q QuerierExecer, fn func(QuerierExecer) error) error {
+	// Begin on a pgx.Tx makes a savepoint, so transactions and savepoints
+	// are begun alike.
+	db, ok := q.(Beginner)
+	if !ok {
+		return fmt.Errorf("can't begin a transaction on a %T", q)
+	}
+	return pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		return fn(tx)
+	})
+}
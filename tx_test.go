@@ -0,0 +1,167 @@
+package pgxtras_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/manniwood/pgxtras"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTx(t *testing.T) {
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		_, err := conn.Exec(ctx, `create temporary table pgxtras_tx_test (n int4)`)
+		require.NoError(t, err)
+		count := func() int32 {
+			var n int32
+			require.NoError(t, conn.QueryRow(ctx, `select count(*) from pgxtras_tx_test`).Scan(&n))
+			return n
+		}
+
+		err = pgxtras.WithTx(ctx, conn, pgx.TxOptions{}, func(tx pgx.Tx) error {
+			_, err := tx.Exec(ctx, `insert into pgxtras_tx_test values (1)`)
+			return err
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), count())
+
+		err = pgxtras.WithTx(ctx, conn, pgx.TxOptions{}, func(tx pgx.Tx) error {
+			_, err := tx.Exec(ctx, `insert into pgxtras_tx_test values (2)`)
+			require.NoError(t, err)
+			return errors.New("changed my mind")
+		})
+		assert.EqualError(t, err, "changed my mind")
+		assert.Equal(t, int32(1), count())
+
+		assert.Panics(t, func() {
+			_ = pgxtras.WithTx(ctx, conn, pgx.TxOptions{}, func(tx pgx.Tx) error {
+				_, err := tx.Exec(ctx, `insert into pgxtras_tx_test values (3)`)
+				require.NoError(t, err)
+				panic("oops")
+			})
+		})
+		assert.Equal(t, int32(1), count())
+	})
+}
+
+func TestWithTxRetry(t *testing.T) {
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		serializationFailure := &pgconn.PgError{Code: "40001"}
+
+		attempts := 0
+		err := pgxtras.WithTx(ctx, conn, pgx.TxOptions{IsoLevel: pgx.Serializable}, func(tx pgx.Tx) error {
+			attempts++
+			if attempts < 3 {
+				return serializationFailure
+			}
+			return nil
+		}, pgxtras.RetryBackoff(time.Millisecond, 10*time.Millisecond))
+		assert.NoError(t, err)
+		assert.Equal(t, 3, attempts)
+
+		attempts = 0
+		err = pgxtras.WithTx(ctx, conn, pgx.TxOptions{IsoLevel: pgx.Serializable}, func(tx pgx.Tx) error {
+			attempts++
+			return serializationFailure
+		}, pgxtras.MaxAttempts(5), pgxtras.RetryBackoff(time.Millisecond, 10*time.Millisecond))
+		assert.ErrorIs(t, err, serializationFailure)
+		assert.Equal(t, 5, attempts)
+
+		// read committed transactions aren't retried
+		attempts = 0
+		err = pgxtras.WithTx(ctx, conn, pgx.TxOptions{}, func(tx pgx.Tx) error {
+			attempts++
+			return serializationFailure
+		})
+		assert.ErrorIs(t, err, serializationFailure)
+		assert.Equal(t, 1, attempts)
+	})
+}
+
+func TestWithTxNested(t *testing.T) {
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		_, err := conn.Exec(ctx, `create temporary table pgxtras_tx_test (n int4)`)
+		require.NoError(t, err)
+
+		err = pgxtras.WithTx(ctx, conn, pgx.TxOptions{}, func(tx pgx.Tx) error {
+			_, err := tx.Exec(ctx, `insert into pgxtras_tx_test values (1)`)
+			require.NoError(t, err)
+
+			err = pgxtras.WithTx(ctx, tx, pgx.TxOptions{}, func(tx pgx.Tx) error {
+				_, err := tx.Exec(ctx, `insert into pgxtras_tx_test values (2)`)
+				require.NoError(t, err)
+				return errors.New("changed my mind")
+			})
+			assert.EqualError(t, err, "changed my mind")
+
+			return pgxtras.WithTx(ctx, tx, pgx.TxOptions{}, func(tx pgx.Tx) error {
+				_, err := tx.Exec(ctx, `insert into pgxtras_tx_test values (3)`)
+				return err
+			})
+		})
+		require.NoError(t, err)
+
+		rows, _ := conn.Query(ctx, `select n from pgxtras_tx_test order by n`)
+		ns, err := pgx.CollectRows(rows, pgx.RowTo[int32])
+		require.NoError(t, err)
+		assert.Equal(t, []int32{1, 3}, ns)
+	})
+}
+
+func TestNested(t *testing.T) {
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		_, err := conn.Exec(ctx, `create temporary table pgxtras_tx_test (n int4)`)
+		require.NoError(t, err)
+		insert := func(n int32) func(pgxtras.QuerierExecer) error {
+			return func(q pgxtras.QuerierExecer) error {
+				_, err := q.Exec(ctx, `insert into pgxtras_tx_test values ($1)`, n)
+				return err
+			}
+		}
+		contents := func() []int32 {
+			rows, _ := conn.Query(ctx, `select n from pgxtras_tx_test order by n`)
+			ns, err := pgx.CollectRows(rows, pgx.RowTo[int32])
+			require.NoError(t, err)
+			return ns
+		}
+
+		// on a conn, a real transaction
+		require.NoError(t, pgxtras.Nested(ctx, conn, insert(1)))
+		err = pgxtras.Nested(ctx, conn, func(q pgxtras.QuerierExecer) error {
+			require.NoError(t, insert(2)(q))
+			return errors.New("changed my mind")
+		})
+		assert.EqualError(t, err, "changed my mind")
+		assert.Equal(t, []int32{1}, contents())
+
+		// in a transaction, savepoints
+		tx, err := conn.Begin(ctx)
+		require.NoError(t, err)
+		require.NoError(t, insert(3)(tx))
+		err = pgxtras.Nested(ctx, tx, func(q pgxtras.QuerierExecer) error {
+			require.NoError(t, insert(4)(q))
+			return pgxtras.Nested(ctx, q, func(q pgxtras.QuerierExecer) error {
+				require.NoError(t, insert(5)(q))
+				return errors.New("changed my mind")
+			})
+		})
+		assert.EqualError(t, err, "changed my mind")
+		require.NoError(t, pgxtras.Nested(ctx, tx, func(q pgxtras.QuerierExecer) error {
+			require.NoError(t, insert(6)(q))
+			return pgxtras.Nested(ctx, q, insert(7))
+		}))
+		assert.Panics(t, func() {
+			_ = pgxtras.Nested(ctx, tx, func(q pgxtras.QuerierExecer) error {
+				require.NoError(t, insert(8)(q))
+				panic("oops")
+			})
+		})
+		require.NoError(t, tx.Commit(ctx))
+		assert.Equal(t, []int32{1, 3, 6, 7}, contents())
+	})
+}
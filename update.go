@@ -0,0 +1,289 @@
+package pgxtras
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// findColumn returns the column named name, or false if there isn't one.
+func findColumn(columns []structColumn, name string) (structColumn, bool) {
+	for _, col := range columns {
+		if col.name == name {
+			return col, true
+		}
+	}
+	return structColumn{}, false
+}
+
+func containsString(strs []string, s string) bool {
+	for _, str := range strs {
+		if str == s {
+			return true
+		}
+	}
+	return false
+}
+
+// buildUpdate returns the UPDATE statement, and its arguments, for writing
+// value to the rows of table whose keyColumns match those of value.
+func buildUpdate(table string, value reflect.Value, columns []structColumn, keyColumns []string, returning bool) (string, []any, error) {
+	if len(keyColumns) == 0 {
+		return "", nil, errors.New("no key columns to update by")
+	}
+	keys := make([]structColumn, len(keyColumns))
+	for i, name := range keyColumns {
+		col, ok := findColumn(columns, name)
+		if !ok {
+			return "", nil, fmt.Errorf("struct %s has no field for key column %s", value.Type(), name)
+		}
+		keys[i] = col
+	}
+
+	var sb strings.Builder
+	var args []any
+	sb.WriteString("update ")
+	sb.WriteString(sanitizeTableName(table))
+	sb.WriteString(" set ")
+	for _, col := range columns {
+		if col.readOnly || containsString(keyColumns, col.name) {
+			continue
+		}
+		fv, ok := fieldByIndexNoAlloc(value, col.index)
+		if col.omitEmpty && (!ok || fv.IsZero()) {
+			continue
+		}
+		if len(args) > 0 {
+			sb.WriteString(", ")
+		}
+		args = append(args, fieldValue(value, col))
+		fmt.Fprintf(&sb, "%s = $%d", pgx.Identifier{col.name}.Sanitize(), len(args))
+	}
+	if len(args) == 0 {
+		return "", nil, fmt.Errorf("struct %s has no columns to update", value.Type())
+	}
+
+	sb.WriteString(" where ")
+	for i, col := range keys {
+		if i > 0 {
+			sb.WriteString(" and ")
+		}
+		args = append(args, fieldValue(value, col))
+		fmt.Fprintf(&sb, "%s = $%d", pgx.Identifier{col.name}.Sanitize(), len(args))
+	}
+
+	if returning {
+		writeReturning(&sb, columns)
+	}
+	return sb.String(), args, nil
+}
+
+// Update writes value, which must be a struct, to the rows of table whose
+// keyColumns have the same values as the fields of value for them, and
+// returns the command tag, which says how many rows were updated. For
+// instance, with the User struct of Insert,
+//
+//	tag, err := pgxtras.Update(ctx, conn, "users", user, "id")
+//
+// runs "update "users" set "name" = $1, "created_at" = $2 where "id" = $3".
+//
+// Fields are written to columns following the same rules as Insert: readonly
+// fields are never written (though they may be key columns), and omitempty
+// fields are left alone when they have their zero values. keyColumns are
+// never written, and must be given, so that Update can't update every row by
+// mistake.
+func Update[T any](ctx context.Context, db Execer, table string, value T, keyColumns ...string) (pgconn.CommandTag, error) {
+	dstElemValue := reflect.ValueOf(&value).Elem()
+	columns, err := structColumns(dstElemValue.Type())
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	sql, args, err := buildUpdate(table, dstElemValue, columns, keyColumns, false)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return db.Exec(ctx, sql, args...)
+}
+
+// UpdateReturning is Update, except that it returns value as it was updated,
+// with every field read back from the updated row. It returns an error
+// matching pgx.ErrNoRows if no row was updated, and a *TooManyRowsError
+// if more than one was. That error is reported after the fact: every row
+// that keyColumns matched has already been updated. To undo the update in
+// that case, call UpdateReturning in a transaction, and roll it back if it
+// returns an error; or use keyColumns that are unique, such as a primary key.
+func UpdateReturning[T any](ctx context.Context, db Querier, table string, value T, keyColumns ...string) (T, error) {
+	dstElemValue := reflect.ValueOf(&value).Elem()
+	columns, err := structColumns(dstElemValue.Type())
+	if err != nil {
+		return value, err
+	}
+	sql, args, err := buildUpdate(table, dstElemValue, columns, keyColumns, true)
+	if err != nil {
+		return value, err
+	}
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return value, err
+	}
+	return scanReturning(rows, value, columns)
+}
+
+// UpsertOption adjusts what Upsert and UpsertReturning do on a conflict.
+type UpsertOption func(*upsertConfig)
+
+type upsertConfig struct {
+	conflictColumns    []string
+	conflictConstraint string
+	excludeFromUpdate  []string
+}
+
+// ConflictColumns sets the conflict target of an upsert to the columns of a
+// unique index or constraint, as in "on conflict (email)".
+func ConflictColumns(columns ...string) UpsertOption {
+	return func(c *upsertConfig) {
+		c.conflictColumns = columns
+	}
+}
+
+// ConflictConstraint sets the conflict target of an upsert to a named unique
+// or exclusion constraint, as in "on conflict on constraint users_email_key".
+func ConflictConstraint(name string) UpsertOption {
+	return func(c *upsertConfig) {
+		c.conflictConstraint = name
+	}
+}
+
+// ExcludeFromUpdate names columns that keep their existing values when an
+// upsert updates a row, such as a created_at column.
+func ExcludeFromUpdate(columns ...string) UpsertOption {
+	return func(c *upsertConfig) {
+		c.excludeFromUpdate = append(c.excludeFromUpdate, columns...)
+	}
+}
+
+// validate checks that c has a conflict target, and that the columns it
+// excludes from updating are columns of structType.
+func (c *upsertConfig) validate(structType reflect.Type, columns []structColumn) error {
+	if len(c.conflictColumns) == 0 && c.conflictConstraint == "" {
+		return errors.New("no conflict target to upsert on; use ConflictColumns or ConflictConstraint")
+	}
+	for _, name := range c.excludeFromUpdate {
+		if _, ok := findColumn(columns, name); !ok {
+			return fmt.Errorf("struct %s has no field for excluded column %s", structType, name)
+		}
+	}
+	return nil
+}
+
+// writeOnConflict writes the ON CONFLICT clause of an upsert of inserted,
+// which updates every inserted column that isn't part of the conflict
+// target and isn't excluded, or does nothing if there are none.
+func (c *upsertConfig) writeOnConflict(sb *strings.Builder, inserted []structColumn) {
+	sb.WriteString(" on conflict ")
+	if c.conflictConstraint != "" {
+		sb.WriteString("on constraint ")
+		sb.WriteString(pgx.Identifier{c.conflictConstraint}.Sanitize())
+	} else {
+		sb.WriteString("(")
+		for i, name := range c.conflictColumns {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(pgx.Identifier{name}.Sanitize())
+		}
+		sb.WriteString(")")
+	}
+
+	var sets []string
+	for _, col := range inserted {
+		if containsString(c.conflictColumns, col.name) || containsString(c.excludeFromUpdate, col.name) {
+			continue
+		}
+		name := pgx.Identifier{col.name}.Sanitize()
+		sets = append(sets, name+" = excluded."+name)
+	}
+	if len(sets) == 0 {
+		sb.WriteString(" do nothing")
+		return
+	}
+	sb.WriteString(" do update set ")
+	sb.WriteString(strings.Join(sets, ", "))
+}
+
+// buildUpsert returns the INSERT ... ON CONFLICT statement, and its
+// arguments, for upserting value into table.
+func buildUpsert(table string, value reflect.Value, columns []structColumn, opts []UpsertOption, returning bool) (string, []any, error) {
+	var c upsertConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if err := c.validate(value.Type(), columns); err != nil {
+		return "", nil, err
+	}
+
+	inserted, args := insertedColumns(value, columns)
+	var sb strings.Builder
+	writeInsert(&sb, table, inserted)
+	c.writeOnConflict(&sb, inserted)
+	if returning {
+		writeReturning(&sb, columns)
+	}
+	return sb.String(), args, nil
+}
+
+// Upsert inserts value, which must be a struct, into table as Insert does,
+// but if that conflicts with an existing row, it updates that row instead,
+// and returns the command tag. The conflict target must be set with
+// ConflictColumns or ConflictConstraint. For instance, given a struct with
+// Email, Name and CreatedAt fields,
+//
+//	tag, err := pgxtras.Upsert(ctx, conn, "users", user,
+//		pgxtras.ConflictColumns("email"), pgxtras.ExcludeFromUpdate("created_at"))
+//
+// runs
+//
+//	insert into "users" ("email", "name", "created_at") values ($1, $2, $3)
+//	on conflict ("email") do update set "name" = excluded."name"
+//
+// Every column that is inserted is updated, except for the conflict
+// columns, and any columns named by ExcludeFromUpdate. If that leaves no
+// columns to update, the conflict is resolved by doing nothing.
+func Upsert[T any](ctx context.Context, db Execer, table string, value T, opts ...UpsertOption) (pgconn.CommandTag, error) {
+	dstElemValue := reflect.ValueOf(&value).Elem()
+	columns, err := structColumns(dstElemValue.Type())
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	sql, args, err := buildUpsert(table, dstElemValue, columns, opts, false)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return db.Exec(ctx, sql, args...)
+}
+
+// UpsertReturning is Upsert, except that it returns value as it was inserted
+// or updated, with every field read back from the row. If the conflict was
+// resolved by doing nothing, no row is returned, and UpsertReturning returns
+// an error matching pgx.ErrNoRows.
+func UpsertReturning[T any](ctx context.Context, db Querier, table string, value T, opts ...UpsertOption) (T, error) {
+	dstElemValue := reflect.ValueOf(&value).Elem()
+	columns, err := structColumns(dstElemValue.Type())
+	if err != nil {
+		return value, err
+	}
+	sql, args, err := buildUpsert(table, dstElemValue, columns, opts, true)
+	if err != nil {
+		return value, err
+	}
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return value, err
+	}
+	return scanReturning(rows, value, columns)
+}
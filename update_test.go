@@ -0,0 +1,92 @@
+package pgxtras_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/manniwood/pgxtras"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type upsertUser struct {
+	ID        int32 `db:"id,readonly"`
+	Email     string
+	Name      string
+	CreatedAt time.Time `db:",omitempty"`
+}
+
+func TestUpdate(t *testing.T) {
+	runWithUsersTable(t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		joe, err := pgxtras.InsertReturning(ctx, conn, "users", upsertUser{Email: "joe@example.com", Name: "Joe"})
+		require.NoError(t, err)
+
+		joe.Name = "Joseph"
+		tag, err := pgxtras.Update(ctx, conn, "users", joe, "id")
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, tag.RowsAffected())
+
+		joe.Name = "Jo"
+		joe.CreatedAt = time.Time{}
+		updated, err := pgxtras.UpdateReturning(ctx, conn, "users", joe, "email")
+		require.NoError(t, err)
+		assert.Equal(t, "Jo", updated.Name)
+		assert.Equal(t, 2020, updated.CreatedAt.UTC().Year())
+
+		joe.ID = 42
+		tag, err = pgxtras.Update(ctx, conn, "users", joe, "id")
+		require.NoError(t, err)
+		assert.EqualValues(t, 0, tag.RowsAffected())
+
+		_, err = pgxtras.UpdateReturning(ctx, conn, "users", joe, "id")
+		assert.True(t, errors.Is(err, pgx.ErrNoRows))
+	})
+}
+
+func TestUpdateErrors(t *testing.T) {
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		_, err := pgxtras.Update(ctx, conn, "users", upsertUser{})
+		assert.EqualError(t, err, "no key columns to update by")
+
+		_, err = pgxtras.Update(ctx, conn, "users", upsertUser{}, "user_id")
+		assert.EqualError(t, err, "struct pgxtras_test.upsertUser has no field for key column user_id")
+	})
+}
+
+func TestUpsert(t *testing.T) {
+	runWithUsersTable(t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		created := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+		user, err := pgxtras.UpsertReturning(ctx, conn, "users",
+			upsertUser{Email: "joe@example.com", Name: "Joe", CreatedAt: created},
+			pgxtras.ConflictColumns("email"), pgxtras.ExcludeFromUpdate("created_at"))
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), user.ID)
+		assert.Equal(t, "Joe", user.Name)
+
+		tag, err := pgxtras.Upsert(ctx, conn, "users",
+			upsertUser{Email: "joe@example.com", Name: "Joseph", CreatedAt: time.Now()},
+			pgxtras.ConflictColumns("email"), pgxtras.ExcludeFromUpdate("created_at"))
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, tag.RowsAffected())
+
+		users := readUsers[upsertUser](t, ctx, conn)
+		require.Len(t, users, 1)
+		assert.Equal(t, "Joseph", users[0].Name)
+		assert.True(t, created.Equal(users[0].CreatedAt))
+
+		_, err = pgxtras.UpsertReturning(ctx, conn, "users",
+			upsertUser{Email: "joe@example.com", Name: "Joe"},
+			pgxtras.ConflictConstraint("users_email_key"), pgxtras.ExcludeFromUpdate("email", "name"))
+		assert.True(t, errors.Is(err, pgx.ErrNoRows))
+	})
+}
+
+func TestUpsertNoConflictTarget(t *testing.T) {
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		_, err := pgxtras.Upsert(ctx, conn, "users", upsertUser{})
+		assert.EqualError(t, err, "no conflict target to upsert on; use ConflictColumns or ConflictConstraint")
+	})
+}
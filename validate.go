@@ -0,0 +1,93 @@
+package pgxtras
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ValidateStructForQuery checks, without running sql, that the rows it returns
+// could be scanned into a T using mapper: that every column matches a field
+// of T (and, unless the LaxMatching option is given, every field of T a
+// column), and that the Postgres type of every column can be scanned into
+// the Go type of its field. It prepares sql as an unnamed statement on conn
+// to learn the columns and their types, so any parameters are left unbound.
+//
+// Everything that is wrong is reported at once, as a MappingErrors if there
+// is more than one thing, so it is handy to run over every query a program
+// uses in a unit test, or at start up:
+//
+//	err := pgxtras.ValidateStructForQuery[User](ctx, conn, `select id, name from users where id = $1`, pgxtras.SimpleNameMapper)
+//
+// To validate using a connection from a pgxpool.Pool, acquire a *pgxpool.Conn
+// and pass its Conn(). Note that a column whose type is only known at run time
+// to be NULL (such as a bare "null as name") is checked as text.
+func ValidateStructForQuery[T any](ctx context.Context, conn *pgx.Conn, sql string, mapper NameMapper, opts ...StructScanOption) error {
+	sd, err := conn.Prepare(ctx, "", sql)
+	if err != nil {
+		return err
+	}
+
+	plans := newStructScanPlanCache(mapper, opts...)
+	structType := reflect.TypeOf((*T)(nil)).Elem()
+	plan, mismatches := plans.matchColumns([]reflect.Type{structType}, ColumnSplit{}, sd.Fields)
+	if plan == nil {
+		return mismatches[0]
+	}
+
+	// The columns that did match fields are checked for type mismatches
+	// even if others didn't, so that everything is reported at once.
+	typeMap := conn.TypeMap()
+	for i, index := range plan.fieldIndexes {
+		if index == nil {
+			continue
+		}
+		oid := sd.Fields[i].DataTypeOID
+		fieldType := fieldTypeByIndex(structType, index)
+		scanPlan := typeMap.PlanScan(oid, typeMap.FormatCodeForOID(oid), reflect.New(fieldType).Interface())
+		if isFailedScanPlan(scanPlan) {
+			mismatches = append(mismatches, &MappingError{
+				Kind:        IncompatibleType,
+				StructType:  structType,
+				FieldName:   plan.fieldNames[i],
+				ColumnName:  sd.Fields[i].Name,
+				ColumnIndex: i,
+				DataTypeOID: oid,
+				FieldType:   fieldType,
+			})
+		}
+	}
+
+	switch len(mismatches) {
+	case 0:
+		return nil
+	case 1:
+		return mismatches[0]
+	default:
+		return mismatches
+	}
+}
+
+// fieldTypeByIndex is the reflect.Type counterpart of fieldByIndexAlloc.
+func fieldTypeByIndex(t reflect.Type, index []int) reflect.Type {
+	for i, x := range index {
+		if i > 0 && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		t = t.Field(x).Type
+	}
+	return t
+}
+
+// failedScanPlanType is the type of the plan that pgtype returns when it can
+// find no way to scan into a target. pgtype doesn't export it, so it is found
+// by asking for a plan to scan a bool into a channel, which nothing can do.
+var failedScanPlanType = reflect.TypeOf(pgtype.NewMap().PlanScan(pgtype.BoolOID, pgtype.BinaryFormatCode, new(chan struct{})))
+
+// isFailedScanPlan reports whether pgtype could find no way to scan into
+// the target it was asked to plan for.
+func isFailedScanPlan(plan pgtype.ScanPlan) bool {
+	return reflect.TypeOf(plan) == failedScanPlanType
+}
@@ -0,0 +1,77 @@
+package pgxtras_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manniwood/pgxtras"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateStructForQuery(t *testing.T) {
+	type person struct {
+		ID        int32
+		FirstName string
+		CreatedAt time.Time
+	}
+
+	defaultConnTestRunner.RunTest(context.Background(), t, func(ctx context.Context, t testing.TB, conn *pgx.Conn) {
+		// the statement is prepared, not run, so $1 needn't be bound
+		err := pgxtras.ValidateStructForQuery[person](ctx, conn,
+			`select $1::int4 as id, 'John'::text as first_name, now() as created_at`, pgxtras.SimpleNameMapper)
+		assert.NoError(t, err)
+
+		err = pgxtras.ValidateStructForQuery[person](ctx, conn,
+			`select 1::int4 as id, 'John'::text as first_name, 42::int4 as created_at`, pgxtras.SimpleNameMapper)
+		assert.EqualError(t, err, "returned column created_at (type OID 23) can't be scanned into struct field CreatedAt of type time.Time")
+		var mappingErr *pgxtras.MappingError
+		require.True(t, errors.As(err, &mappingErr))
+		assert.Equal(t, pgxtras.IncompatibleType, mappingErr.Kind)
+		assert.Equal(t, reflect.TypeOf(person{}), mappingErr.StructType)
+		assert.Equal(t, 2, mappingErr.ColumnIndex)
+		assert.Equal(t, uint32(pgtype.Int4OID), mappingErr.DataTypeOID)
+		assert.Equal(t, reflect.TypeOf(time.Time{}), mappingErr.FieldType)
+
+		// everything wrong is reported at once
+		err = pgxtras.ValidateStructForQuery[person](ctx, conn,
+			`select 1::int4 as id, 'John'::text as name`, pgxtras.SimpleNameMapper)
+		var mappingErrs pgxtras.MappingErrors
+		require.True(t, errors.As(err, &mappingErrs))
+		require.Len(t, mappingErrs, 3)
+		assert.Equal(t, pgxtras.MissingColumn, mappingErrs[0].Kind)
+		assert.Equal(t, pgxtras.MissingColumn, mappingErrs[1].Kind)
+		assert.Equal(t, pgxtras.UnmappedColumn, mappingErrs[2].Kind)
+
+		// including type mismatches of the columns that did match
+		err = pgxtras.ValidateStructForQuery[person](ctx, conn,
+			`select 'x'::text as id, 'John'::text as name`, pgxtras.SimpleNameMapper)
+		mappingErrs = nil
+		require.True(t, errors.As(err, &mappingErrs))
+		require.Len(t, mappingErrs, 4)
+		assert.Equal(t, pgxtras.MissingColumn, mappingErrs[0].Kind)
+		assert.Equal(t, pgxtras.MissingColumn, mappingErrs[1].Kind)
+		assert.Equal(t, pgxtras.UnmappedColumn, mappingErrs[2].Kind)
+		assert.Equal(t, pgxtras.IncompatibleType, mappingErrs[3].Kind)
+		assert.Equal(t, 0, mappingErrs[3].ColumnIndex)
+
+		err = pgxtras.ValidateStructForQuery[person](ctx, conn,
+			`select 1::int4 as id, 'John'::text as name`, pgxtras.SimpleNameMapper, pgxtras.LaxMatching())
+		assert.NoError(t, err)
+
+		// jsonb can be scanned into anything encoding/json can unmarshal into
+		err = pgxtras.ValidateStructForQuery[struct{ Data map[string]any }](ctx, conn,
+			`select '{}'::jsonb as data`, pgxtras.SimpleNameMapper)
+		assert.NoError(t, err)
+
+		err = pgxtras.ValidateStructForQuery[person](ctx, conn, `select from nowhere`, pgxtras.SimpleNameMapper)
+		var pgErr *pgconn.PgError
+		assert.True(t, errors.As(err, &pgErr))
+	})
+}